@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ekotlikoff/gofit/internal/images"
+	"github.com/ekotlikoff/gofit/internal/model"
+	"github.com/ekotlikoff/gofit/internal/static"
+)
+
+const imagesUsage = `usage: gofit images <command> [flags]
+
+commands:
+  import  copy exported images into movement_images
+  check   report missing and orphaned images
+`
+
+const defaultImagesDir = "internal/static/webpage/movement_images"
+
+// imagesCommand imports and checks movement images. It returns the process
+// exit code, 1 if any images are missing or orphaned.
+func imagesCommand(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, imagesUsage)
+		return 2
+	}
+	flags := flag.NewFlagSet("images "+args[0], flag.ExitOnError)
+	movementsPath := flags.String("movements", "", "movements json or yaml to check against, defaults to the embedded bank")
+	dest := flags.String("dest", defaultImagesDir, "movement_images directory")
+	format := flags.String("format", "text", "output format, json or text")
+	var options images.ImportOptions
+	switch args[0] {
+	case "import":
+		flags.StringVar(&options.Source, "src", "", "directory of exported images named like Movement_Name_Iteration.png")
+		flags.IntVar(&options.MaxSize, "max-size", images.DefaultMaxSize, "largest width or height in pixels")
+		flags.BoolVar(&options.WebP, "webp", false, "also write a lossless webp of each image, needs cwebp")
+		flags.BoolVar(&options.DryRun, "dry-run", false, "report what would be imported without writing")
+	case "check":
+	default:
+		fmt.Fprint(os.Stderr, imagesUsage)
+		return 2
+	}
+	flags.Parse(args[1:])
+	options.Dest = *dest
+
+	// The bank's images are what's being imported or checked, so it is
+	// decoded without requiring them.
+	data := static.MovementsFS
+	if *movementsPath != "" {
+		var err error
+		if data, err = model.ReadBankFile(*movementsPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	bank, err := model.DecodeBank(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var report images.Report
+	if args[0] == "import" {
+		if options.Source == "" {
+			fmt.Fprintln(os.Stderr, "-src is required")
+			return 2
+		}
+		if report, err = images.Import(options, bank); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	} else {
+		report = images.Check(os.DirFS(*dest), bank)
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	case "text":
+		for _, name := range report.Imported {
+			fmt.Println("imported", name)
+		}
+		for _, name := range report.Unmatched {
+			fmt.Println("unmatched", name)
+		}
+		for _, name := range report.Missing {
+			fmt.Println("missing", name)
+		}
+		for _, name := range report.Orphaned {
+			fmt.Println("orphaned", name)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown format", *format)
+		return 2
+	}
+	if len(report.Missing) > 0 || len(report.Orphaned) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,7 +1,36 @@
 package main
 
-import "github.com/ekotlikoff/gofit/pkg/fitserver"
+import (
+	"fmt"
+	"os"
+
+	"github.com/ekotlikoff/gofit/pkg/fitserver"
+)
+
+const usage = `usage: gofit [command]
+
+With no command the server is run.
+
+commands:
+  validate-movements  check a movement bank for problems
+  images              import and check movement images
+  simulate            report on many workouts generated from preferences
+`
 
 func main() {
-	fitserver.RunServer()
+	if len(os.Args) < 2 {
+		fitserver.RunServer()
+		return
+	}
+	switch os.Args[1] {
+	case "validate-movements":
+		os.Exit(validateMovements(os.Args[2:]))
+	case "images":
+		os.Exit(imagesCommand(os.Args[2:]))
+	case "simulate":
+		os.Exit(simulate(os.Args[2:]))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+	"sigs.k8s.io/yaml"
+)
+
+// simulate generates many workouts from one set of preferences and reports
+// their durations, ratios, movements, focus coverage and fallbacks. It
+// returns the process exit code.
+func simulate(args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	preferencesPath := flags.String("preferences", "", "json or yaml WorkoutPreferences, defaults to the default preferences")
+	duration := flags.Duration("duration", 0, "overrides the preferences' max duration")
+	workoutFormat := flags.String("workout-format", "", "workout format to generate, defaults to sequential")
+	runs := flags.Int("runs", 1000, "number of workouts to generate")
+	seed := flags.Int64("seed", 1, "seed of the first workout, each run adds one")
+	movementsPath := flags.String("movements", "", "movements.json to use, defaults to the embedded bank")
+	format := flags.String("format", "text", "output format, json or text")
+	flags.Parse(args)
+
+	preferences := model.DefaultWorkoutPreferences()
+	if *preferencesPath != "" {
+		data, err := os.ReadFile(*preferencesPath)
+		if err == nil {
+			err = yaml.Unmarshal(data, &preferences)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *duration != 0 {
+		preferences.Effort.MaxDuration = *duration
+	}
+	if err := preferences.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	options := model.WorkoutOptions{Preferences: preferences, Seed: *seed, Format: model.Format(*workoutFormat)}
+	if options.Format != "" && !model.ValidFormat(options.Format) {
+		fmt.Fprintln(os.Stderr, "unknown workout format", *workoutFormat)
+		return 2
+	}
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "-runs must be at least 1")
+		return 2
+	}
+	if *movementsPath != "" {
+		if err := model.SetBankSource(*movementsPath, ""); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	report, err := model.Simulate(options, *runs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	case "text":
+		printSimulation(report)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown format", *format)
+		return 2
+	}
+	return 0
+}
+
+func printSimulation(report model.SimulationReport) {
+	fmt.Printf("%d workouts, %d failed", report.Runs, report.Failures)
+	if report.Unsatisfiable > 0 {
+		fmt.Printf(", %d unsatisfiable", report.Unsatisfiable)
+	}
+	fmt.Println()
+	violations := []string{}
+	for name := range report.Violations {
+		violations = append(violations, name)
+	}
+	sort.Strings(violations)
+	for _, name := range violations {
+		fmt.Printf("  %s violated %d times\n", name, report.Violations[name])
+	}
+	fmt.Printf("fallbacks in %d workouts\n", report.Fallbacks)
+	fmt.Println("\n              min    mean    p10    p50    p90    max")
+	printSummary("minutes", report.Minutes)
+	ratios := []string{}
+	for name := range report.Ratios {
+		ratios = append(ratios, name)
+	}
+	sort.Strings(ratios)
+	for _, name := range ratios {
+		printSummary(name, report.Ratios[name])
+	}
+	fmt.Println("\nfocus coverage")
+	for _, f := range report.Focus {
+		fmt.Printf("  %-10s %5.1f%%\n", f.Name, 100*f.Share)
+	}
+	fmt.Println("\nmovements")
+	for _, m := range report.Movements {
+		fmt.Printf("  %5.1f%%  %s\n", 100*m.Share, m.Name)
+	}
+}
+
+func printSummary(name string, s model.Summary) {
+	fmt.Printf("%-12s %6.2f %7.2f %6.2f %6.2f %6.2f %6.2f\n", name, s.Min, s.Mean, s.P10, s.P50, s.P90, s.Max)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+	"github.com/ekotlikoff/gofit/internal/static"
+)
+
+// validateMovements checks a movement bank and its images, printing the
+// problems found as json or text. It returns the process exit code.
+func validateMovements(args []string) int {
+	flags := flag.NewFlagSet("validate-movements", flag.ExitOnError)
+	movementsPath := flags.String("movements", "", "movements json or yaml to check, defaults to the embedded bank")
+	imagesPath := flags.String("images", "", "directory containing movement_images, defaults to the embedded images")
+	format := flags.String("format", "json", "output format, json or text")
+	flags.Parse(args)
+
+	data := static.MovementsFS
+	if *movementsPath != "" {
+		var err error
+		if data, err = model.ReadBankFile(*movementsPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	var images fs.FS
+	if *imagesPath != "" {
+		images = os.DirFS(*imagesPath)
+	} else {
+		images, _ = fs.Sub(static.WebpageStaticFS, "webpage")
+	}
+
+	problems := model.ValidateBank(data, images)
+	switch *format {
+	case "json":
+		result := struct {
+			Valid    bool                `json:"valid"`
+			Problems []model.BankProblem `json:"problems"`
+		}{len(problems) == 0, problems}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	case "text":
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown format", *format)
+		return 2
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -5,6 +5,9 @@ go 1.22
 require (
 	github.com/gofrs/uuid v4.4.0+incompatible
 	github.com/prometheus/client_golang v1.19.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/image v0.18.0
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -0,0 +1,220 @@
+// Package images imports exported movement illustrations into the
+// movement_images directory the web page serves.
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Exports may be jpegs.
+	"image/png"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+	"golang.org/x/image/draw"
+)
+
+// DefaultMaxSize is the largest width or height an imported image is given.
+const DefaultMaxSize = 800
+
+type (
+	// ImportOptions configure Import.
+	ImportOptions struct {
+		// Source is the directory of exported images, named like
+		// "Downward_Facing_Dog_Active.png".
+		Source string
+		// Dest is the movement_images directory.
+		Dest string
+		// MaxSize bounds the width and height of imported images, images
+		// are never enlarged. Zero means DefaultMaxSize.
+		MaxSize int
+		// WebP also writes a lossless webp next to each png, with cwebp
+		// which must be on the PATH.
+		WebP bool
+		// DryRun reports what would be imported without writing anything.
+		DryRun bool
+	}
+
+	// Report describes the state of a movement_images directory.
+	Report struct {
+		// Imported are the images written, relative to Dest.
+		Imported []string `json:"imported"`
+		// Unmatched are source files that name no movement iteration.
+		Unmatched []string `json:"unmatched"`
+		// Missing are images the bank expects that don't exist.
+		Missing []string `json:"missing"`
+		// Orphaned are images that belong to no movement iteration.
+		Orphaned []string `json:"orphaned"`
+	}
+)
+
+// ParseName maps an exported file name to the movement and iteration it
+// shows. The name is lower cased, underscores become spaces and the last
+// word is the iteration, so "Downward_Facing_Dog_Active.png" is the active
+// iteration of "downward facing dog".
+func ParseName(filename string) (movement, iteration string, ok bool) {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	words := strings.Fields(strings.ReplaceAll(strings.ToLower(name), "_", " "))
+	if len(words) < 2 {
+		return "", "", false
+	}
+	return strings.Join(words[:len(words)-1], " "), words[len(words)-1], true
+}
+
+// Import resizes and re-encodes the exported images in options.Source into
+// options.Dest, then checks Dest against the movement bank.
+func Import(options ImportOptions, bank []model.Movement) (Report, error) {
+	if options.MaxSize == 0 {
+		options.MaxSize = DefaultMaxSize
+	}
+	if options.WebP && !options.DryRun {
+		if _, err := exec.LookPath("cwebp"); err != nil {
+			return Report{}, fmt.Errorf("webp output needs cwebp: %w", err)
+		}
+	}
+	iterations := bankIterations(bank)
+	entries, err := os.ReadDir(options.Source)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Imported: []string{}, Unmatched: []string{}}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		movement, iteration, ok := ParseName(entry.Name())
+		if !ok || !iterations[movement][iteration] {
+			report.Unmatched = append(report.Unmatched, entry.Name())
+			continue
+		}
+		dest := path.Join(movement, iteration+".png")
+		if !options.DryRun {
+			err := importImage(filepath.Join(options.Source, entry.Name()),
+				filepath.Join(options.Dest, filepath.FromSlash(dest)), options)
+			if err != nil {
+				return report, fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+		}
+		report.Imported = append(report.Imported, dest)
+	}
+	check := Check(os.DirFS(options.Dest), bank)
+	report.Missing, report.Orphaned = check.Missing, check.Orphaned
+	if options.DryRun {
+		// Pretend the imported images were written.
+		report.Missing = subtract(report.Missing, report.Imported)
+	}
+	return report, nil
+}
+
+// Check compares the images in a movement_images directory with the images
+// the movements expect.
+func Check(images fs.FS, bank []model.Movement) Report {
+	iterations := bankIterations(bank)
+	report := Report{Missing: []string{}, Orphaned: []string{}}
+	for _, movement := range bank {
+		for _, iteration := range movement.Iterations() {
+			name := path.Join(movement.Name, iteration+".png")
+			if _, err := fs.Stat(images, name); err != nil {
+				report.Missing = append(report.Missing, name)
+			}
+		}
+	}
+	fs.WalkDir(images, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		ext := path.Ext(name)
+		iteration := strings.TrimSuffix(path.Base(name), ext)
+		if (ext != ".png" && ext != ".webp") || !iterations[path.Dir(name)][iteration] {
+			report.Orphaned = append(report.Orphaned, name)
+		}
+		return nil
+	})
+	sort.Strings(report.Missing)
+	sort.Strings(report.Orphaned)
+	return report
+}
+
+// importImage writes src to dest as an optimized png no larger than
+// options.MaxSize, and as a webp if requested.
+func importImage(src, dest string, options ImportOptions) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+	img = Resize(img, options.MaxSize)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dest, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if !options.WebP {
+		return nil
+	}
+	cwebp := exec.Command("cwebp", "-quiet", "-lossless", dest, "-o", strings.TrimSuffix(dest, ".png")+".webp")
+	if output, err := cwebp.CombinedOutput(); err != nil {
+		return fmt.Errorf("cwebp: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// Resize scales img down so neither side is longer than maxSize, keeping
+// its aspect ratio.
+func Resize(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return img
+	}
+	if width >= height {
+		width, height = maxSize, max(1, height*maxSize/width)
+	} else {
+		width, height = max(1, width*maxSize/height), maxSize
+	}
+	resized := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+	return resized
+}
+
+// bankIterations indexes each movement's iterations by movement name.
+func bankIterations(bank []model.Movement) map[string]map[string]bool {
+	iterations := map[string]map[string]bool{}
+	for _, movement := range bank {
+		iterations[movement.Name] = map[string]bool{}
+		for _, iteration := range movement.Iterations() {
+			iterations[movement.Name][iteration] = true
+		}
+	}
+	return iterations
+}
+
+func subtract(names, remove []string) []string {
+	removed := map[string]bool{}
+	for _, name := range remove {
+		removed[name] = true
+	}
+	result := []string{}
+	for _, name := range names {
+		if !removed[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
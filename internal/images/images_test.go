@@ -0,0 +1,189 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+	"golang.org/x/image/webp"
+)
+
+var testBank = []model.Movement{{Name: "bridge"}}
+
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 7), uint8(y * 3), uint8(x ^ y), 0xff})
+		}
+	}
+	return img
+}
+
+func writePNG(t *testing.T, name string, img image.Image) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		filename, movement, iteration string
+		ok                            bool
+	}{
+		{"Downward_Facing_Dog_Active.png", "downward facing dog", "active", true},
+		{"bridge rest.jpg", "bridge", "rest", true},
+		{"Clamshell_Left.PNG", "clamshell", "left", true},
+		{"active.png", "", "", false},
+	}
+	for _, test := range tests {
+		movement, iteration, ok := ParseName(test.filename)
+		if movement != test.movement || iteration != test.iteration || ok != test.ok {
+			t.Errorf("ParseName(%q) = %q, %q, %v, want %q, %q, %v", test.filename,
+				movement, iteration, ok, test.movement, test.iteration, test.ok)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	img := testImage(400, 100)
+	if got := Resize(img, 200).Bounds(); got != image.Rect(0, 0, 200, 50) {
+		t.Errorf("resized to %v", got)
+	}
+	if got := Resize(img, 1000); got != image.Image(img) {
+		t.Error("small images should not be enlarged")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	bank := []model.Movement{
+		{Name: "bridge"},
+		{Name: "clamshell", IterationNames: []string{"left", "right"}},
+	}
+	images := fstest.MapFS{
+		"bridge/active.png":    {},
+		"bridge/rest.png":      {},
+		"bridge/rest.webp":     {},
+		"bridge/notes.txt":     {},
+		"clamshell/left.png":   {},
+		"clamshell/active.png": {},
+		"old move/active.png":  {},
+	}
+	report := Check(images, bank)
+	assertNames(t, "missing", report.Missing, "clamshell/rest.png", "clamshell/right.png")
+	assertNames(t, "orphaned", report.Orphaned, "bridge/notes.txt",
+		"clamshell/active.png", "old move/active.png")
+}
+
+func TestImport(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	writePNG(t, filepath.Join(source, "Bridge_Active.png"), testImage(1200, 600))
+	writePNG(t, filepath.Join(source, "Not_A_Movement_Active.png"), testImage(4, 4))
+	writePNG(t, filepath.Join(dest, "retired move", "active.png"), testImage(4, 4))
+
+	report, err := Import(ImportOptions{Source: source, Dest: dest, MaxSize: 300}, testBank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, "imported", report.Imported, "bridge/active.png")
+	assertNames(t, "unmatched", report.Unmatched, "Not_A_Movement_Active.png")
+	assertNames(t, "orphaned", report.Orphaned, "retired move/active.png")
+	for _, missing := range report.Missing {
+		if missing == "bridge/active.png" {
+			t.Error("imported image reported missing")
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dest, "bridge", "active.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	config, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 300 || config.Height != 150 {
+		t.Errorf("imported image is %dx%d, want 300x150", config.Width, config.Height)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "bridge", "active.webp")); err == nil {
+		t.Error("webp written without being requested")
+	}
+}
+
+func TestImportWebP(t *testing.T) {
+	if _, err := exec.LookPath("cwebp"); err != nil {
+		t.Skip("cwebp is not installed")
+	}
+	source, dest := t.TempDir(), t.TempDir()
+	img := testImage(40, 20)
+	writePNG(t, filepath.Join(source, "Bridge_Active.png"), img)
+	if _, err := Import(ImportOptions{Source: source, Dest: dest, WebP: true}, testBank); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(dest, "bridge", "active.webp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	decoded, err := webp.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("webp bounds %v, want %v", decoded.Bounds(), img.Bounds())
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	writePNG(t, filepath.Join(source, "Bridge_Rest.png"), testImage(4, 4))
+	report, err := Import(ImportOptions{Source: source, Dest: dest, DryRun: true}, testBank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNames(t, "imported", report.Imported, "bridge/rest.png")
+	if _, err := os.Stat(filepath.Join(dest, "bridge")); !os.IsNotExist(err) {
+		t.Error("dry run wrote to dest")
+	}
+	for _, missing := range report.Missing {
+		if missing == "bridge/rest.png" {
+			t.Error("dry run import reported missing")
+		}
+	}
+}
+
+func assertNames(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %q, want %q", what, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s = %q, want %q", what, got, want)
+		}
+	}
+}
+
+func TestImportWebPNeedsCwebp(t *testing.T) {
+	t.Setenv("PATH", "")
+	_, err := Import(ImportOptions{Source: t.TempDir(), Dest: t.TempDir(), WebP: true}, testBank)
+	if err == nil {
+		t.Error("expected an error without cwebp")
+	}
+}
@@ -0,0 +1,240 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/static"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// bankLock guards the movement bank and where it is loaded from.
+	bankLock sync.RWMutex
+	// movementBank is baseBank with customMovements applied.
+	movementBank    []Movement
+	baseBank        []Movement
+	customMovements []Movement
+	bankPath        string
+	bankImagesDir   string
+	bankModTime     time.Time
+	// reloadLock serializes reloads so a slow one can't clobber a newer one.
+	reloadLock sync.Mutex
+)
+
+// SetBankSource loads the movement bank from an external movements file,
+// json or yaml, with images from the movement_images directory in imagesDir.
+// Images missing from imagesDir fall back to the embedded ones. An empty path
+// restores the embedded bank.
+func SetBankSource(path, imagesDir string) error {
+	bankLock.Lock()
+	bankPath, bankImagesDir = path, imagesDir
+	bankLock.Unlock()
+	return ReloadBank()
+}
+
+// ReloadBank rereads and validates the movement bank. If the new bank is
+// invalid the current one is kept.
+func ReloadBank() error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	bankLock.RLock()
+	path := bankPath
+	bankLock.RUnlock()
+	data, modTime := static.MovementsFS, time.Time{}
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return &BankError{Err: err}
+		}
+		if data, err = ReadBankFile(path); err != nil {
+			return &BankError{Err: err}
+		}
+		modTime = info.ModTime()
+	}
+	bank, err := parseBank(data, BankImages())
+	if err != nil {
+		return err
+	}
+	bankLock.Lock()
+	baseBank, bankModTime = bank, modTime
+	movementBank = mergeBank(baseBank, customMovements)
+	bankLock.Unlock()
+	return nil
+}
+
+// SetCustomMovements applies movements managed at runtime on top of the
+// bank. Each replaces any bank movement with the same name.
+func SetCustomMovements(movements []Movement) error {
+	if _, err := currentBank(); err != nil {
+		return err
+	}
+	bankLock.Lock()
+	defer bankLock.Unlock()
+	customMovements = movements
+	movementBank = mergeBank(baseBank, customMovements)
+	return nil
+}
+
+// Movements returns every movement in the bank, including retired ones.
+func Movements() ([]Movement, error) {
+	bank, err := currentBank()
+	return append([]Movement{}, bank...), err
+}
+
+// BaseMovement returns the movement as loaded from the bank file, before
+// any custom movements are applied.
+func BaseMovement(name string) (Movement, bool) {
+	bankLock.RLock()
+	defer bankLock.RUnlock()
+	for _, movement := range baseBank {
+		if movement.Name == name {
+			return movement, true
+		}
+	}
+	return Movement{}, false
+}
+
+func mergeBank(base, custom []Movement) []Movement {
+	merged := append([]Movement{}, base...)
+	for _, movement := range custom {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == movement.Name {
+				merged[i], replaced = movement, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, movement)
+		}
+	}
+	return merged
+}
+
+// WatchBank polls an external movement bank, reloading it when it changes,
+// until done is closed. Each reload's result is passed to reloaded.
+func WatchBank(interval time.Duration, done <-chan struct{}, reloaded func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bankLock.RLock()
+			path, modTime := bankPath, bankModTime
+			bankLock.RUnlock()
+			if path == "" {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(modTime) {
+				continue
+			}
+			err = ReloadBank()
+			if err != nil {
+				// Don't retry until the file changes again.
+				bankLock.Lock()
+				bankModTime = info.ModTime()
+				bankLock.Unlock()
+			}
+			reloaded(err)
+		case <-done:
+			return
+		}
+	}
+}
+
+// BankImages returns the file system holding the movement_images directory.
+func BankImages() fs.FS {
+	embedded, _ := fs.Sub(static.WebpageStaticFS, "webpage")
+	bankLock.RLock()
+	imagesDir := bankImagesDir
+	bankLock.RUnlock()
+	if imagesDir == "" {
+		return embedded
+	}
+	return overlayFS{os.DirFS(imagesDir), embedded}
+}
+
+// BankSize returns how many movements are in the bank.
+func BankSize() int {
+	bank, _ := currentBank()
+	return len(bank)
+}
+
+// currentBank returns the movement bank, loading it on first use.
+func currentBank() ([]Movement, error) {
+	bankLock.RLock()
+	bank := movementBank
+	bankLock.RUnlock()
+	if bank != nil {
+		return bank, nil
+	}
+	if err := ReloadBank(); err != nil {
+		return nil, err
+	}
+	bankLock.RLock()
+	defer bankLock.RUnlock()
+	return movementBank, nil
+}
+
+// loadMovementBank forces the bank to be reloaded from its source.
+func loadMovementBank() error {
+	return ReloadBank()
+}
+
+// parseBank validates and decodes a movements.json document.
+// ReadBankFile reads a movements file, converting yaml to json.
+func ReadBankFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		return yaml.YAMLToJSON(data)
+	}
+	return data, nil
+}
+
+// DecodeBank validates and decodes a movements.json document without
+// checking its images, for tools that manage the images themselves.
+func DecodeBank(data []byte) ([]Movement, error) {
+	return parseBank(data, nil)
+}
+
+func parseBank(data []byte, images fs.FS) ([]Movement, error) {
+	if problems := ValidateBank(data, images); len(problems) > 0 {
+		return nil, &BankError{Err: errors.New("invalid movement bank"), Problems: problems}
+	}
+	var bank []Movement
+	if err := json.Unmarshal(data, &bank); err != nil {
+		return nil, &BankError{Err: err}
+	}
+	if len(bank) == 0 {
+		return nil, &BankError{Err: errors.New("no movements")}
+	}
+	for i, m := range bank {
+		bank[i].Duration = m.Duration * time.Second
+	}
+	return bank, nil
+}
+
+// overlayFS opens files from the first file system that has them.
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	var err error
+	for _, fsys := range o {
+		var f fs.File
+		if f, err = fsys.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
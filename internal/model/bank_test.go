@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const yamlBank = `
+- name: squat
+  reps: 2
+  duration: 5
+  position: standing
+  modality: strength
+  focus: [hip, knee]
+  effort: high
+- name: bridge
+  reps: 15
+  duration: 5
+  position: ground
+  modality: strength
+  focus: [hip, back]
+  requirement: [mat]
+  effort: medium
+`
+
+func TestExternalBankReloadsAndRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movements.yaml")
+	if err := os.WriteFile(path, []byte(yamlBank), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetBankSource(path, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBankSource("", "")
+	if size := BankSize(); size != 2 {
+		t.Fatalf("expected 2 movements, got %d", size)
+	}
+	bank, _ := currentBank()
+	if bank[0].Duration != 5*time.Second {
+		t.Errorf("expected durations in seconds, got %s", bank[0].Duration)
+	}
+
+	if err := os.WriteFile(path, []byte("- name: squat\n  reps: 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var bankErr *BankError
+	if err := ReloadBank(); !errors.As(err, &bankErr) || len(bankErr.Problems) == 0 {
+		t.Errorf("expected validation problems, got %v", err)
+	}
+	if size := BankSize(); size != 2 {
+		t.Errorf("expected the previous bank to be kept, got %d movements", size)
+	}
+}
+
+func TestWatchBankReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movements.yaml")
+	os.WriteFile(path, []byte(yamlBank), 0644)
+	if err := SetBankSource(path, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer SetBankSource("", "")
+	done := make(chan struct{})
+	defer close(done)
+	reloaded := make(chan error, 1)
+	go WatchBank(10*time.Millisecond, done, func(err error) { reloaded <- err })
+
+	oneMovement := yamlBank[:strings.Index(yamlBank, "- name: bridge")]
+	os.WriteFile(path, []byte(oneMovement), 0644)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the bank to be reloaded")
+	}
+	if size := BankSize(); size != 1 {
+		t.Errorf("expected 1 movement after reloading, got %d", size)
+	}
+}
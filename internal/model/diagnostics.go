@@ -0,0 +1,97 @@
+package model
+
+import "time"
+
+type (
+	// Diagnostics explain how a workout was generated.
+	Diagnostics struct {
+		// Durations are the phase lengths drawn from the structure
+		// preference.
+		Durations PhaseDurations `json:"durations"`
+		// Slots describe how each of the workout's movements was chosen.
+		Slots []Slot `json:"slots,omitempty"`
+		// Fallbacks are where no movement had the phase's efforts, so
+		// movements of any effort were allowed.
+		Fallbacks []Fallback `json:"fallbacks,omitempty"`
+		// Attempts is how many workouts the solver built, Cost is the
+		// chosen one's.
+		Attempts int     `json:"attempts,omitempty"`
+		Cost     float64 `json:"cost,omitempty"`
+	}
+	// PhaseDurations are the planned lengths of a workout's phases.
+	PhaseDurations struct {
+		Warmup     time.Duration `json:"warmup"`
+		HighEffort time.Duration `json:"highEffort"`
+		Cooldown   time.Duration `json:"cooldown"`
+		Standing   time.Duration `json:"standing"`
+		Ground     time.Duration `json:"ground"`
+	}
+	// Slot is one movement's choice.
+	Slot struct {
+		Movement string   `json:"movement"`
+		Phase    string   `json:"phase"`
+		Position Position `json:"position"`
+		Efforts  []Effort `json:"efforts"`
+		// Pool is how many movements matched the slot, Candidates how many
+		// of those weren't ruled out and fit the time left.
+		Pool       int  `json:"pool"`
+		Candidates int  `json:"candidates"`
+		Fallback   bool `json:"fallback,omitempty"`
+		// phase is Phase before it was named.
+		phase WorkoutEffortPhase
+	}
+	// Fallback is a phase and position no movement matched.
+	Fallback struct {
+		Phase    string   `json:"phase"`
+		Position Position `json:"position"`
+		Efforts  []Effort `json:"efforts"`
+	}
+)
+
+func (phase WorkoutEffortPhase) String() string {
+	switch phase {
+	case WarmupPhase:
+		return "warmup"
+	case HighEffortPhase:
+		return "high effort"
+	case CooldownPhase:
+		return "cooldown"
+	}
+	return "unknown"
+}
+
+// phaseDurations names the durations from getWorkoutDurations.
+func phaseDurations(durations []time.Duration) PhaseDurations {
+	return PhaseDurations{Warmup: durations[0], HighEffort: durations[1], Cooldown: durations[2],
+		Standing: durations[3], Ground: durations[4]}
+}
+
+// newSlot describes choosing a movement for the segment from the pool.
+func newSlot(seg segment, options WorkoutOptions, p pool) Slot {
+	return Slot{Phase: seg.Phase.String(), Position: options.Profile.position(seg.Position),
+		Efforts: effortsForPhase(seg.Phase), Pool: len(p.Movements), Fallback: p.Fallback, phase: seg.Phase}
+}
+
+// movementSlots are the phase and position of each slot, one per movement.
+func (diagnostics *Diagnostics) movementSlots() []MovementSlot {
+	slots := make([]MovementSlot, len(diagnostics.Slots))
+	for i, slot := range diagnostics.Slots {
+		slots[i] = MovementSlot{Phase: slot.phase, Position: slot.Position}
+	}
+	return slots
+}
+
+// slotFallbacks are the phases and positions of the slots filled from a
+// fallback, in order.
+func slotFallbacks(slots []Slot) []Fallback {
+	fallbacks := []Fallback{}
+	seen := map[string]bool{}
+	for _, slot := range slots {
+		key := slot.Phase + " " + string(slot.Position)
+		if slot.Fallback && !seen[key] {
+			seen[key] = true
+			fallbacks = append(fallbacks, Fallback{Phase: slot.Phase, Position: slot.Position, Efforts: slot.Efforts})
+		}
+	}
+	return fallbacks
+}
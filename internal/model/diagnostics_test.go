@@ -0,0 +1,131 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkoutDiagnostics(t *testing.T) {
+	mustLoadMovementBank(t)
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 5}
+	workout, err := MakeWorkoutWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workout.Diagnostics != nil {
+		t.Errorf("expected no diagnostics unless asked for")
+	}
+	options.Debug = true
+	debug, err := MakeWorkoutWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(debug.Movements) != len(workout.Movements) {
+		t.Errorf("expected diagnostics not to change the workout")
+	}
+	diagnostics := debug.Diagnostics
+	if diagnostics == nil || len(diagnostics.Slots) != len(debug.Movements) {
+		t.Fatalf("expected a slot per movement, got %+v", diagnostics)
+	}
+	for i, slot := range diagnostics.Slots {
+		if slot.Movement != debug.Movements[i].Name || slot.Candidates == 0 || slot.Candidates > slot.Pool {
+			t.Errorf("slot %d is %+v for %s", i, slot, debug.Movements[i].Name)
+		}
+	}
+	durations := diagnostics.Durations
+	total := durations.Warmup + durations.HighEffort + durations.Cooldown
+	if diff := options.Preferences.Effort.MaxDuration - total; diff < 0 || diff > time.Second {
+		t.Errorf("phase durations add up to %s", total)
+	}
+	if diagnostics.Attempts != SolverAttempts {
+		t.Errorf("expected %d attempts, got %d", SolverAttempts, diagnostics.Attempts)
+	}
+}
+
+func TestDiagnosticsReportFallbacks(t *testing.T) {
+	// No ground movement is low effort, so the cooldown falls back.
+	movementBank = []Movement{
+		{Name: "march", Reps: 10, Duration: time.Second, Position: Standing, Effort: Low},
+		{Name: "squat", Reps: 10, Duration: time.Second, Position: Standing, Effort: High},
+		{Name: "plank", Reps: 1, Duration: 30 * time.Second, Position: Ground, Effort: High},
+	}
+	defer mustLoadMovementBank(t)
+	preferences := DefaultWorkoutPreferences()
+	preferences.Effort.MaxDuration = 5 * time.Minute
+	s, err := buildSolution(newTestRand(t), WorkoutOptions{Preferences: preferences}, map[string]pool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, fallback := range s.diagnostics.Fallbacks {
+		found = found || fallback.Position == Ground
+	}
+	if !found {
+		t.Errorf("expected a ground fallback, got %+v", s.diagnostics.Fallbacks)
+	}
+}
+
+func TestStructuredWorkoutDiagnostics(t *testing.T) {
+	mustLoadMovementBank(t)
+	for format := range formatGenerators {
+		t.Run(string(format), func(t *testing.T) {
+			preferences := DefaultWorkoutPreferences()
+			preferences.Effort.MaxDuration = 20 * time.Minute
+			workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: preferences, Seed: 3,
+				Format: format, Debug: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if workout.Diagnostics == nil || len(workout.Diagnostics.Slots) != len(workout.Movements) {
+				t.Fatalf("expected a slot per step, got %+v", workout.Diagnostics)
+			}
+			for i, slot := range workout.Diagnostics.Slots {
+				if slot.Movement != workout.Movements[i].Name || slot.Candidates == 0 || slot.Candidates > slot.Pool ||
+					!contains(slot.Efforts, workout.Movements[i].Effort) {
+					t.Errorf("slot %d is %+v for %s", i, slot, workout.Movements[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestStructuredDiagnosticsReportFallbacks(t *testing.T) {
+	// No ground movement is low effort, so the cooldown falls back.
+	movementBank = []Movement{
+		{Name: "march", Reps: 10, Duration: time.Second, Position: Standing, Effort: Low},
+		{Name: "squat", Reps: 10, Duration: time.Second, Position: Standing, Effort: High},
+		{Name: "plank", Reps: 1, Duration: 30 * time.Second, Position: Ground, Effort: High},
+	}
+	defer mustLoadMovementBank(t)
+	workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(),
+		Format: Circuit, Debug: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallbacks := workout.Diagnostics.Fallbacks
+	if len(fallbacks) != 1 || fallbacks[0].Phase != "cooldown" || fallbacks[0].Position != Ground {
+		t.Errorf("expected a ground cooldown fallback, got %+v", fallbacks)
+	}
+}
+
+func TestConstraintErrorHasDiagnostics(t *testing.T) {
+	// Every movement takes five minutes, so only one fits in eight.
+	movementBank = []Movement{}
+	for _, position := range allPositions {
+		for _, effort := range allEfforts {
+			movementBank = append(movementBank, Movement{Name: string(position) + " " + string(effort),
+				Reps: 100, Duration: time.Second, Position: position, Effort: effort})
+		}
+	}
+	defer mustLoadMovementBank(t)
+	_, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Debug: true})
+	var constraint *ConstraintError
+	if !errors.As(err, &constraint) {
+		t.Fatalf("expected a ConstraintError, got %v", err)
+	}
+	if constraint.Diagnostics == nil || len(constraint.Diagnostics.Slots) == 0 ||
+		constraint.Diagnostics.Attempts != SolverAttempts {
+		t.Errorf("expected the closest workout's diagnostics, got %+v", constraint.Diagnostics)
+	}
+}
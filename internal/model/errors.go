@@ -0,0 +1,40 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSwapIndex is returned when swapping a movement that was already done
+// or isn't in the workout.
+var ErrSwapIndex = errors.New("no movement left to do at that index")
+
+type (
+	// BankError is returned when the movement bank cannot be loaded.
+	BankError struct {
+		Err error
+		// Problems found validating the bank, if any.
+		Problems []BankProblem
+	}
+	// UnsatisfiableError is returned when no movement in the bank meets the
+	// preferences for a slot of the workout, even ignoring effort.
+	UnsatisfiableError struct {
+		Position Position
+		Efforts  []Effort
+	}
+)
+
+func (e *BankError) Error() string {
+	if len(e.Problems) > 0 {
+		return fmt.Sprintf("loading movement bank: %v, %d problems, first %s", e.Err, len(e.Problems), e.Problems[0])
+	}
+	return fmt.Sprintf("loading movement bank: %v", e.Err)
+}
+
+func (e *BankError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnsatisfiableError) Error() string {
+	return fmt.Sprintf("no %s movements with effort %s meet the preferences", e.Position, e.Efforts)
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MinRPE and MaxRPE bound the rating of perceived exertion.
+	MinRPE = 1
+	MaxRPE = 10
+	// Workouts rated between TargetMinRPE and TargetMaxRPE are about right
+	// and leave the effort preference as it is.
+	TargetMinRPE = 5
+	TargetMaxRPE = 7
+	// EffortStep is the fraction the multipliers change by after a workout
+	// rated outside the target range.
+	EffortStep = 0.1
+	// FlagStep is the fraction the multipliers change by for each movement
+	// flagged too easy, or back for each flagged too hard.
+	FlagStep = 0.02
+	// MinMultiplier and MaxMultiplier bound how far feedback can scale reps
+	// and durations.
+	MinMultiplier = 0.5
+	MaxMultiplier = 3
+	// DurationStep is how much longer or shorter the next workouts get after
+	// a workout rated outside the target range.
+	DurationStep = 2 * time.Minute
+	// MinAdaptiveDuration is the shortest feedback makes a workout.
+	MinAdaptiveDuration = 4 * time.Minute
+)
+
+// Feedback is a user's rating of a workout they did.
+type Feedback struct {
+	// RPE is the rating of perceived exertion, MinRPE to MaxRPE.
+	RPE int `json:"rpe"`
+	// TooEasy and TooHard name movements in the workout the user flagged.
+	TooEasy []string `json:"tooEasy"`
+	TooHard []string `json:"tooHard"`
+}
+
+// Validate checks the rating is in range and the flagged movements are in
+// the workout, each flagged at most once.
+func (feedback Feedback) Validate(workout Workout) error {
+	if feedback.RPE < MinRPE || feedback.RPE > MaxRPE {
+		return fmt.Errorf("rpe must be between %d and %d", MinRPE, MaxRPE)
+	}
+	names := []string{}
+	for _, movement := range workout.Movements {
+		names = append(names, movement.Name)
+	}
+	flagged := map[string]bool{}
+	for _, name := range append(append([]string{}, feedback.TooEasy...), feedback.TooHard...) {
+		if !containsName(names, name) {
+			return fmt.Errorf("%q is not in the workout", name)
+		} else if flagged[name] {
+			return fmt.Errorf("%q is flagged more than once", name)
+		}
+		flagged[name] = true
+	}
+	return nil
+}
+
+// Adjust returns the effort preference for the user's next workouts. A
+// workout that felt easy makes them longer with more reps and time per
+// movement, one that felt hard the opposite, and flagged movements nudge the
+// multipliers.
+func (preference EffortPreference) Adjust(feedback Feedback) EffortPreference {
+	direction := 0
+	if feedback.RPE < TargetMinRPE {
+		direction = 1
+	} else if feedback.RPE > TargetMaxRPE {
+		direction = -1
+	}
+	if scale := 1 + EffortStep*float64(direction) +
+		FlagStep*float64(len(feedback.TooEasy)-len(feedback.TooHard)); scale != 1 {
+		preference.RepMultiplier = scaleMultiplier(preference.RepMultiplier, scale)
+		preference.DurationMultiplier = scaleMultiplier(preference.DurationMultiplier, scale)
+	}
+	if direction != 0 {
+		preference.MaxDuration = min(MaxWorkoutDuration, max(MinAdaptiveDuration,
+			preference.maxDuration()+time.Duration(direction)*DurationStep))
+	}
+	return preference
+}
+
+// scaleMultiplier scales a multiplier, keeping it between MinMultiplier and
+// MaxMultiplier.
+func scaleMultiplier(multiplier float32, scale float64) float32 {
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	return float32(min(MaxMultiplier, max(MinMultiplier, float64(multiplier)*scale)))
+}
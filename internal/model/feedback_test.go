@@ -0,0 +1,60 @@
+package model
+
+import "testing"
+
+func TestAdjustFollowsRPE(t *testing.T) {
+	effort := DefaultWorkoutPreferences().Effort
+	easy := effort.Adjust(Feedback{RPE: 3})
+	if easy.RepMultiplier <= effort.RepMultiplier || easy.DurationMultiplier <= effort.DurationMultiplier ||
+		easy.MaxDuration != effort.MaxDuration+DurationStep {
+		t.Errorf("expected an easy workout to make the next harder, got %+v", easy)
+	}
+	hard := effort.Adjust(Feedback{RPE: 9})
+	if hard.RepMultiplier >= effort.RepMultiplier || hard.MaxDuration != effort.MaxDuration-DurationStep {
+		t.Errorf("expected a hard workout to make the next easier, got %+v", hard)
+	}
+	if right := effort.Adjust(Feedback{RPE: TargetMinRPE}); right != effort {
+		t.Errorf("expected a workout in the target range to change nothing, got %+v", right)
+	}
+	flagged := effort.Adjust(Feedback{RPE: 6, TooHard: []string{"squat", "lunge"}})
+	if flagged.RepMultiplier >= effort.RepMultiplier || flagged.MaxDuration != effort.MaxDuration {
+		t.Errorf("expected flags to only nudge the multipliers, got %+v", flagged)
+	}
+}
+
+func TestAdjustStaysInBounds(t *testing.T) {
+	effort := DefaultWorkoutPreferences().Effort
+	for i := 0; i < 100; i++ {
+		effort = effort.Adjust(Feedback{RPE: MaxRPE})
+	}
+	if effort.RepMultiplier != MinMultiplier || effort.MaxDuration != MinAdaptiveDuration {
+		t.Errorf("expected the floors, got %+v", effort)
+	}
+	for i := 0; i < 100; i++ {
+		effort = effort.Adjust(Feedback{RPE: MinRPE})
+	}
+	if effort.DurationMultiplier != MaxMultiplier || effort.MaxDuration != MaxWorkoutDuration {
+		t.Errorf("expected the ceilings, got %+v", effort)
+	}
+	if err := effort.validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFeedbackValidate(t *testing.T) {
+	workout := Workout{Movements: []Movement{{Name: "squat"}, {Name: "bridge"}}}
+	cases := map[string]Feedback{
+		"rpe too low":    {RPE: 0},
+		"rpe too high":   {RPE: 11},
+		"not in workout": {RPE: 5, TooEasy: []string{"lunge"}},
+		"flagged twice":  {RPE: 5, TooEasy: []string{"squat"}, TooHard: []string{"squat"}},
+	}
+	for name, feedback := range cases {
+		if feedback.Validate(workout) == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := (Feedback{RPE: 5, TooHard: []string{"bridge"}}).Validate(workout); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,415 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	// Sequential workouts play each movement once, in order.
+	Sequential = Format("sequential")
+	// Circuit workouts repeat a block of movements for several rounds.
+	Circuit = Format("circuit")
+	// Superset workouts alternate two movements for a few sets.
+	Superset = Format("superset")
+	// Tabata workouts alternate fixed work and rest windows.
+	Tabata = Format("tabata")
+	// EMOM workouts start a movement every minute on the minute.
+	EMOM = Format("emom")
+	// AMRAP workouts repeat a block for as many rounds as possible.
+	AMRAP = Format("amrap")
+
+	CircuitSize     = 4
+	SupersetSets    = 3
+	TabataWork      = 20 * time.Second
+	TabataRest      = 10 * time.Second
+	TabataIntervals = 8
+	EMOMInterval    = time.Minute
+	// EMOMWork is the most of each minute an EMOM movement may take.
+	EMOMWork  = 40 * time.Second
+	EMOMSize  = 3
+	AMRAPSize = 3
+)
+
+var allFormats = []Format{Sequential, Circuit, Superset, Tabata, EMOM, AMRAP}
+
+type (
+	// Format is how a workout's movements are structured.
+	Format string
+	// Block is a structured section of a workout. Its steps are
+	// Workout.Movements[Start:End], played in order.
+	Block struct {
+		Format Format `json:"format"`
+		Start  int    `json:"start"`
+		End    int    `json:"end"`
+		// Rounds the block's movements are repeated for.
+		Rounds int `json:"rounds"`
+		// Interval blocks start a step every Interval, whatever is left of
+		// the window after the step's work is rest.
+		Interval time.Duration `json:"interval,omitempty"`
+		// TimeCap ends an AMRAP block, steps not reached by then are skipped.
+		TimeCap time.Duration `json:"timeCap,omitempty"`
+	}
+	// formatGenerator builds the main blocks of a workout lasting about
+	// duration from the candidates. Block Start and End are relative to the
+	// returned steps.
+	formatGenerator func(rng *rand.Rand, options WorkoutOptions, candidates []Movement, duration time.Duration) ([]Movement, []Block)
+)
+
+var formatGenerators = map[Format]formatGenerator{
+	Circuit:  makeCircuit,
+	Superset: makeSupersets,
+	Tabata:   makeTabata,
+	EMOM:     makeEMOM,
+	AMRAP:    makeAMRAP,
+}
+
+// ValidFormat reports whether format is a known workout format.
+func ValidFormat(format Format) bool {
+	for _, f := range allFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentBlock returns the block holding the next step to play.
+func (workout Workout) CurrentBlock() (Block, bool) {
+	return workout.blockAt(workout.Done)
+}
+
+func (workout Workout) blockAt(step int) (Block, bool) {
+	for _, block := range workout.Blocks {
+		if step >= block.Start && step < block.End {
+			return block, true
+		}
+	}
+	return Block{}, false
+}
+
+// EndBlock skips the rest of the current block once its time cap has
+// elapsed. It reports false if the current block has no time cap.
+func (workout *Workout) EndBlock() bool {
+	block, ok := workout.CurrentBlock()
+	if !ok || block.TimeCap == 0 {
+		return false
+	}
+	workout.Done = block.End
+	return true
+}
+
+// makeStructuredWorkout surrounds a format's main blocks with a low effort
+// standing warmup and ground cooldown, as in a sequential workout.
+func makeStructuredWorkout(rng *rand.Rand, options WorkoutOptions) ([]Movement, []Block, *Diagnostics, error) {
+	generate, ok := formatGenerators[options.Format]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unknown workout format %q", options.Format)
+	}
+	preferences := options.Preferences
+	durations := getWorkoutDurations(rng, preferences)
+	pools := map[string]pool{}
+	warmup, warmupSlots := fillSequence(rng, options, segment{Phase: WarmupPhase, Position: Standing}, durations[0], pools)
+	cooldown, cooldownSlots := fillSequence(rng, options, segment{Phase: CooldownPhase, Position: Ground}, durations[2], pools)
+	remaining := preferences.Effort.maxDuration() - sequenceDuration(warmup) - sequenceDuration(cooldown)
+	candidates, err := mainOptions(options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	main, mainBlocks := generate(rng, options, candidates.Movements, remaining)
+	steps, blocks := []Movement{}, []Block{}
+	appendBlocks := func(movements []Movement, added []Block) {
+		for _, block := range added {
+			block.Start += len(steps)
+			block.End += len(steps)
+			blocks = append(blocks, block)
+		}
+		steps = append(steps, movements...)
+	}
+	if len(warmup) > 0 {
+		appendBlocks(warmup, []Block{{Format: Sequential, End: len(warmup), Rounds: 1}})
+	}
+	appendBlocks(main, mainBlocks)
+	if len(cooldown) > 0 {
+		appendBlocks(cooldown, []Block{{Format: Sequential, End: len(cooldown), Rounds: 1}})
+	}
+	// The main blocks choose from all of their candidates.
+	slots := warmupSlots
+	for _, step := range main {
+		slot := newSlot(segment{Phase: HighEffortPhase, Position: step.Position}, options, candidates)
+		slot.Movement, slot.Candidates = step.Name, len(candidates.Movements)
+		slots = append(slots, slot)
+	}
+	slots = append(slots, cooldownSlots...)
+	return steps, blocks, &Diagnostics{Durations: phaseDurations(durations), Slots: slots,
+		Fallbacks: slotFallbacks(slots)}, nil
+}
+
+// fillSequence picks movements for the segment's phase and position until
+// duration is used up, with the slot each was chosen in. It may pick none.
+func fillSequence(rng *rand.Rand, options WorkoutOptions, seg segment, duration time.Duration,
+	pools map[string]pool) ([]Movement, []Slot) {
+	p, err := segmentPool(seg, options, pools)
+	if err != nil {
+		// No movement is in the position, the phase is left out.
+		return nil, nil
+	}
+	slot := newSlot(seg, options, p)
+	selection, slots := []Movement{}, []Slot{}
+	for {
+		fitting := fitWithin(options.ruleOut(p.Movements, selection), duration-sequenceDuration(selection))
+		if len(fitting) == 0 {
+			return selection, slots
+		}
+		movement := choose(rng, fitting, selection, options)
+		slot.Movement, slot.Candidates = movement.Name, len(fitting)
+		selection, slots = append(selection, movement), append(slots, slot)
+	}
+}
+
+// mainOptions are the medium and high effort movements for a format's main
+// blocks, standing before ground, falling back to any effort if there are
+// none.
+func mainOptions(options WorkoutOptions) (pool, error) {
+	query := func(efforts []Effort) []Movement {
+		candidates := []Movement{}
+		for _, position := range []Position{Standing, Ground} {
+			candidates = append(candidates, queryMovements(position, efforts, options)...)
+		}
+		return candidates
+	}
+	p := pool{Movements: query(effortsForPhase(HighEffortPhase))}
+	if len(p.Movements) == 0 {
+		p = pool{Movements: query(allEfforts), Fallback: true}
+		if len(p.Movements) == 0 {
+			return pool{}, &UnsatisfiableError{Position: Standing, Efforts: allEfforts}
+		}
+	}
+	preferences := options.Preferences
+	p.Movements = options.Timings.calibrate(preferences.Effort.scaleAll(filterByFocus(p.Movements, preferences.Focus)))
+	return p, nil
+}
+
+// preferModality restricts options to the modality if at least n match.
+func preferModality(options []Movement, modality Modality, n int) []Movement {
+	matched := []Movement{}
+	for _, movement := range options {
+		if movement.Modality == modality {
+			matched = append(matched, movement)
+		}
+	}
+	if len(matched) >= n {
+		return matched
+	}
+	return options
+}
+
+// pickDistinct chooses up to n differently named movements, keeping
+// standing movements before ground ones.
+func pickDistinct(rng *rand.Rand, candidates []Movement, n int, options WorkoutOptions) []Movement {
+	picked := []Movement{}
+	for len(picked) < n && len(candidates) > 0 {
+		movement := choose(rng, candidates, picked, options)
+		picked = append(picked, movement)
+		remaining := []Movement{}
+		for _, candidate := range candidates {
+			if candidate.Name != movement.Name {
+				remaining = append(remaining, candidate)
+			}
+		}
+		candidates = remaining
+	}
+	ordered := []Movement{}
+	for _, position := range []Position{Standing, Ground} {
+		for _, movement := range picked {
+			if movement.Position == position {
+				ordered = append(ordered, movement)
+			}
+		}
+	}
+	return ordered
+}
+
+func sequenceDuration(movements []Movement) time.Duration {
+	total := time.Duration(0)
+	for _, movement := range movements {
+		total += movement.EstimateDuration() + EstimatedRestPerMovement
+	}
+	return total
+}
+
+func repeat(movements []Movement, rounds int) []Movement {
+	steps := []Movement{}
+	for i := 0; i < rounds; i++ {
+		steps = append(steps, movements...)
+	}
+	return steps
+}
+
+// fitReps cuts the movement's reps so it takes no longer than window.
+func fitReps(movement Movement, window time.Duration) Movement {
+	if movement.EstimateDuration() <= window {
+		return movement
+	}
+	perRep := movement.EstimateDuration() / time.Duration(movement.Reps)
+	movement.Reps = max(1, int(window/perRep))
+	if movement.SwitchSides && movement.Reps%2 == 1 && movement.Reps > 1 {
+		movement.Reps--
+	}
+	return movement
+}
+
+// fitToBlock adapts a movement to the windows of an interval block.
+func fitToBlock(movement Movement, block Block) Movement {
+	switch block.Format {
+	case Tabata:
+		return intervalStep(movement, TabataWork)
+	case EMOM:
+		return fitReps(movement, EMOMWork)
+	}
+	return movement
+}
+
+// intervalStep turns the movement into a single continuous effort lasting
+// work, shared between its iterations.
+func intervalStep(movement Movement, work time.Duration) Movement {
+	iterations := max(1, len(movement.IterationNames))
+	movement.Reps = 1
+	movement.IterationsPerRep = 0
+	movement.SwitchSides = false
+	movement.Duration = max(time.Second, (work / time.Duration(iterations)).Round(time.Second))
+	return movement
+}
+
+// fitSequence drops movements from the end, then cuts the reps of the one
+// left, until the movements take no longer than duration. It returns none
+// if a single rep doesn't fit.
+func fitSequence(movements []Movement, duration time.Duration) []Movement {
+	movements = append([]Movement{}, movements...)
+	for len(movements) > 1 && sequenceDuration(movements) > duration {
+		movements = movements[:len(movements)-1]
+	}
+	if len(movements) == 1 {
+		movements[0] = fitReps(movements[0], duration-EstimatedRestPerMovement)
+	}
+	if sequenceDuration(movements) > duration {
+		return nil
+	}
+	return movements
+}
+
+// stepDurations estimates how long each step of a workout takes. Interval
+// block steps last their window and AMRAP blocks end at their time cap, the
+// steps past it take no time.
+func stepDurations(steps []Movement, blocks []Block) []time.Duration {
+	durations := make([]time.Duration, len(steps))
+	for i, step := range steps {
+		durations[i] = step.EstimateDuration() + EstimatedRestPerMovement
+	}
+	for _, block := range blocks {
+		elapsed := time.Duration(0)
+		for i := block.Start; i < block.End; i++ {
+			switch {
+			case block.Interval > 0:
+				durations[i] = block.Interval
+			case block.TimeCap > 0:
+				durations[i] = min(durations[i], block.TimeCap-elapsed)
+				elapsed += durations[i]
+			}
+		}
+	}
+	return durations
+}
+
+// makeCircuit repeats a block of strength movements for as many rounds as
+// fit, with fewer movements if a round wouldn't.
+func makeCircuit(rng *rand.Rand, options WorkoutOptions, candidates []Movement, duration time.Duration) ([]Movement, []Block) {
+	movements := pickDistinct(rng, preferModality(candidates, Strength, CircuitSize), CircuitSize, options)
+	if movements = fitSequence(movements, duration); len(movements) == 0 {
+		return nil, nil
+	}
+	rounds := int(duration / sequenceDuration(movements))
+	steps := repeat(movements, rounds)
+	return steps, []Block{{Format: Circuit, End: len(steps), Rounds: rounds}}
+}
+
+// makeSupersets alternates pairs of strength movements for SupersetSets
+// sets, adding pairs while they fit. The first pair is shrunk to fit.
+func makeSupersets(rng *rand.Rand, options WorkoutOptions, candidates []Movement, duration time.Duration) ([]Movement, []Block) {
+	pool := pickDistinct(rng, preferModality(candidates, Strength, 2), len(candidates), options)
+	steps, blocks := []Movement{}, []Block{}
+	for len(pool) > 0 {
+		pair := pool[:min(2, len(pool))]
+		pool = pool[len(pair):]
+		budget := (duration - sequenceDuration(steps)) / SupersetSets
+		if len(blocks) == 0 {
+			pair = fitSequence(pair, budget)
+		}
+		if len(pair) == 0 || sequenceDuration(pair) > budget {
+			break
+		}
+		sets := repeat(pair, SupersetSets)
+		blocks = append(blocks, Block{Format: Superset, Start: len(steps),
+			End: len(steps) + len(sets), Rounds: SupersetSets})
+		steps = append(steps, sets...)
+	}
+	return steps, blocks
+}
+
+// makeTabata alternates two movements through TabataIntervals windows of
+// TabataWork then TabataRest, with as many tabatas as fit. If a whole
+// tabata doesn't fit it is cut to the windows that do.
+func makeTabata(rng *rand.Rand, options WorkoutOptions, candidates []Movement, duration time.Duration) ([]Movement, []Block) {
+	interval := TabataWork + TabataRest
+	count, intervals := int(duration/(interval*TabataIntervals)), TabataIntervals
+	if count == 0 {
+		// Keep the windows even so both movements get as many.
+		count, intervals = 1, int(duration/interval)/2*2
+		if intervals == 0 {
+			return nil, nil
+		}
+	}
+	steps, blocks := []Movement{}, []Block{}
+	for i := 0; i < count; i++ {
+		pair := pickDistinct(rng, candidates, 2, options)
+		blocks = append(blocks, Block{Format: Tabata, Start: len(steps),
+			End: len(steps) + intervals, Rounds: intervals / len(pair), Interval: interval})
+		for j := 0; j < intervals; j++ {
+			steps = append(steps, intervalStep(pair[j%len(pair)], TabataWork))
+		}
+	}
+	return steps, blocks
+}
+
+// makeEMOM cycles through up to EMOMSize movements, one every minute, each
+// cut to fit in EMOMWork.
+func makeEMOM(rng *rand.Rand, options WorkoutOptions, candidates []Movement, duration time.Duration) ([]Movement, []Block) {
+	minutes := int(duration / EMOMInterval)
+	movements := pickDistinct(rng, candidates, min(EMOMSize, minutes), options)
+	if len(movements) == 0 {
+		return nil, nil
+	}
+	for i := range movements {
+		movements[i] = fitReps(movements[i], EMOMWork)
+	}
+	rounds := minutes / len(movements)
+	steps := repeat(movements, rounds)
+	return steps, []Block{{Format: EMOM, End: len(steps), Rounds: rounds, Interval: EMOMInterval}}
+}
+
+// makeAMRAP repeats a block until its time cap. The block holds twice the
+// rounds expected to fit, the client ends it when the cap elapses.
+func makeAMRAP(rng *rand.Rand, options WorkoutOptions, candidates []Movement, duration time.Duration) ([]Movement, []Block) {
+	timeCap := duration.Truncate(time.Minute)
+	if timeCap == 0 {
+		timeCap = duration.Truncate(time.Second)
+	}
+	movements := fitSequence(pickDistinct(rng, candidates, AMRAPSize, options), timeCap)
+	if timeCap <= 0 || len(movements) == 0 {
+		return nil, nil
+	}
+	rounds := 2 * int(timeCap/sequenceDuration(movements))
+	steps := repeat(movements, rounds)
+	return steps, []Block{{Format: AMRAP, End: len(steps), Rounds: rounds, TimeCap: timeCap}}
+}
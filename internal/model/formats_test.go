@@ -0,0 +1,153 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustMakeFormat(t *testing.T, format Format, seed int64) Workout {
+	t.Helper()
+	preferences := DefaultWorkoutPreferences()
+	preferences.Effort.MaxDuration = 20 * time.Minute
+	workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: preferences, Seed: seed, Format: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workout
+}
+
+func TestStructuredFormatsBlocksCoverMovements(t *testing.T) {
+	mustLoadMovementBank(t)
+	for format := range formatGenerators {
+		t.Run(string(format), func(t *testing.T) {
+			workout := mustMakeFormat(t, format, newTestRand(t).Int63())
+			if workout.Format != format {
+				t.Errorf("format %q, want %q", workout.Format, format)
+			}
+			next, sawFormat := 0, false
+			for _, block := range workout.Blocks {
+				if block.Start != next || block.End <= block.Start || block.Rounds < 1 {
+					t.Fatalf("block %+v does not follow step %d", block, next)
+				}
+				next = block.End
+				sawFormat = sawFormat || block.Format == format
+			}
+			if next != len(workout.Movements) {
+				t.Errorf("blocks end at step %d of %d", next, len(workout.Movements))
+			}
+			if !sawFormat {
+				t.Errorf("no %s block in %+v", format, workout.Blocks)
+			}
+		})
+	}
+}
+
+func TestStructuredFormatsFitMaxDuration(t *testing.T) {
+	mustLoadMovementBank(t)
+	rng := newTestRand(t)
+	for format := range formatGenerators {
+		for _, maxDuration := range []time.Duration{4 * time.Minute, 8 * time.Minute, 13 * time.Minute, 30 * time.Minute} {
+			preferences := DefaultWorkoutPreferences()
+			preferences.Effort.MaxDuration = maxDuration
+			for i := 0; i < 20; i++ {
+				options := WorkoutOptions{Preferences: preferences, Seed: rng.Int63(), Format: format}
+				workout, err := MakeWorkoutWithOptions(options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				total := time.Duration(0)
+				for _, d := range stepDurations(workout.Movements, workout.Blocks) {
+					total += d
+				}
+				if total > maxDuration {
+					t.Errorf("%s workout with seed %d takes %s, over %s",
+						format, options.Seed, total, maxDuration)
+				}
+			}
+		}
+	}
+}
+
+func TestCircuitRepeatsRounds(t *testing.T) {
+	mustLoadMovementBank(t)
+	workout := mustMakeFormat(t, Circuit, 1)
+	for _, block := range workout.Blocks {
+		if block.Format != Circuit {
+			continue
+		}
+		steps := workout.Movements[block.Start:block.End]
+		size := len(steps) / block.Rounds
+		if size == 0 || size > CircuitSize || len(steps)%block.Rounds != 0 {
+			t.Fatalf("%d steps don't divide into %d rounds", len(steps), block.Rounds)
+		}
+		for i := size; i < len(steps); i++ {
+			if steps[i].Name != steps[i-size].Name {
+				t.Errorf("round %d differs at %s", i/size, steps[i].Name)
+			}
+		}
+		for _, step := range steps[:size] {
+			if step.Effort == Low {
+				t.Errorf("circuit includes low effort %s", step.Name)
+			}
+		}
+	}
+}
+
+func TestIntervalFormatsFitTheirWindows(t *testing.T) {
+	mustLoadMovementBank(t)
+	for _, test := range []struct {
+		format Format
+		window time.Duration
+	}{{Tabata, TabataWork}, {EMOM, EMOMWork}} {
+		workout := mustMakeFormat(t, test.format, 3)
+		for _, block := range workout.Blocks {
+			if block.Format != test.format {
+				continue
+			}
+			if block.Interval == 0 {
+				t.Errorf("%s block has no interval", test.format)
+			}
+			for _, step := range workout.Movements[block.Start:block.End] {
+				if step.EstimateDuration() > test.window+TabataRest {
+					t.Errorf("%s step %s takes %s", test.format, step.Name, step.EstimateDuration())
+				}
+			}
+		}
+	}
+}
+
+func TestAMRAPEndBlock(t *testing.T) {
+	mustLoadMovementBank(t)
+	workout := mustMakeFormat(t, AMRAP, 5)
+	var amrap Block
+	for _, block := range workout.Blocks {
+		if block.Format == AMRAP {
+			amrap = block
+		}
+	}
+	if amrap.TimeCap == 0 {
+		t.Fatal("AMRAP block has no time cap")
+	}
+	if amrap.Start > 0 && workout.EndBlock() {
+		t.Fatal("ended the warmup, which has no time cap")
+	}
+	workout.Done = amrap.Start + 1
+	if !workout.EndBlock() || workout.Done != amrap.End {
+		t.Errorf("expected EndBlock to skip to step %d, at %d", amrap.End, workout.Done)
+	}
+}
+
+func TestStructuredFormatsAreDeterministic(t *testing.T) {
+	mustLoadMovementBank(t)
+	if !reflect.DeepEqual(mustMakeFormat(t, Superset, 9), mustMakeFormat(t, Superset, 9)) {
+		t.Error("expected identical workouts for the same seed")
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Format: "yoga"})
+	if err == nil || ValidFormat("yoga") {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
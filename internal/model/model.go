@@ -2,19 +2,16 @@ package model
 
 import (
 	_ "embed"
-	"encoding/json"
-	"fmt"
+	"math"
 	"math/rand"
 	"time"
-
-	"github.com/ekotlikoff/gofit/internal/static"
 )
 
 const (
 	Standing        = Position("standing")
 	Ground          = Position("ground")
 	Strength        = Modality("strength")
-	Flexibility     = Modality("flexiblity")
+	Flexibility     = Modality("flexibility")
 	Mobility        = Modality("mobility")
 	Hip             = Focus("hip")
 	Back            = Focus("back")
@@ -45,11 +42,17 @@ const (
 	DefaultMaxHighEffortRatio float64       = 1.0 / 2.0
 	DefaultMinCooldownRatio   float64       = 1.0 / 4.0
 	DefaultMaxCooldownRatio   float64       = 1.0 / 3.0
+	EstimatedRestPerMovement  time.Duration = time.Second * 2
+	// FavoriteWeight is how many times more often favorite movements are
+	// chosen.
+	FavoriteWeight = 3.0
+	// MinFocusOptions is the fewest focused candidates a slot needs before
+	// selection is restricted to them rather than merely biased toward them.
+	MinFocusOptions = 2
 )
 
 var (
-	movementBank []Movement
-	allEfforts   = []Effort{Low, Medium, High}
+	allEfforts = []Effort{Low, Medium, High}
 )
 
 type (
@@ -76,93 +79,274 @@ type (
 		SwitchSides      bool          `json:"switchSides"`
 		Requirement      []Requirement `json:"requirement"`
 		Effort           Effort        `json:"effort"`
+		// Retired movements are kept for history but never selected.
+		Retired bool `json:"retired,omitempty"`
+		// Contraindications are loads users with matching limitations avoid.
+		Contraindications []Contraindication `json:"contraindications,omitempty"`
+		// Regression names an easier variant, used in its place for users
+		// it conflicts with.
+		Regression string `json:"regression,omitempty"`
+		// Progression names a harder variant, used in its place once the
+		// user has mastered it.
+		Progression string `json:"progression,omitempty"`
+		// Estimate replaces EstimateDuration when set, calibrated from the
+		// user's measured sessions.
+		Estimate time.Duration `json:"estimate,omitempty"`
 	}
 	Workout struct {
+		// Movements are the steps to play in order, structured formats
+		// repeat movements as described by Blocks.
 		Movements []Movement `json:"movements"`
 		Done      int        `json:"done"`
+		Format    Format     `json:"format"`
+		// Blocks structure the movements, sequential workouts have none.
+		Blocks []Block `json:"blocks,omitempty"`
+		// Seed the workout was generated with, the same user, day and seed
+		// regenerate the identical workout.
+		Seed int64 `json:"seed"`
+		// Slots are what each movement was chosen for, so a swap picks a
+		// replacement for the same slot.
+		Slots []MovementSlot `json:"slots,omitempty"`
+		// Scheduled is the program session the workout was built for.
+		Scheduled *ScheduledDay `json:"scheduled,omitempty"`
+		// Diagnostics explain the workout, if the options asked for them.
+		Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+	}
+	// WorkoutOptions configure how a workout is generated.
+	WorkoutOptions struct {
+		Preferences WorkoutPreferences
+		Seed        int64
+		// Format defaults to Sequential.
+		Format Format
+		// Swapped counts how often the user swapped each movement out of a
+		// workout, those movements are chosen less often.
+		Swapped map[string]int
+		// Blocked movements are never chosen.
+		Blocked []string
+		// Favorites are chosen FavoriteWeight times as often.
+		Favorites []string
+		// Profile's limitations exclude or regress conflicting movements.
+		Profile Profile
+		// Mastered movements are replaced by their progressions.
+		Mastered []string
+		// Recent movements are chosen less often and balance focus coverage.
+		Recent []RecentMovement
+		// Score weighs candidate movements, DefaultScore if nil.
+		Score Scorer
+		// Scheduled overrides the format, duration and focus with a
+		// program's session.
+		Scheduled *ScheduledDay
+		// Debug attaches Diagnostics to the workout.
+		Debug bool
+		// Timings calibrate the movements' estimated durations.
+		Timings Timings
+	}
+	// MovementSlot is the effort phase and position a workout movement was
+	// chosen for.
+	MovementSlot struct {
+		Phase    WorkoutEffortPhase `json:"phase"`
+		Position Position           `json:"position"`
 	}
 	WorkoutEffortPhase   int
 	WorkoutPositionPhase int
 	WorkoutPreferences   struct {
-		Structure StructurePreference
-		Effort    EffortPreference
-		// TODO the below two are unused.
-		Focus []Focus
-		Other OtherPreference
+		Structure StructurePreference `json:"structure"`
+		Effort    EffortPreference    `json:"effort"`
+		// Focus areas to restrict or bias movement selection toward, empty
+		// means no preference.
+		Focus []Focus         `json:"focus"`
+		Other OtherPreference `json:"other"`
 	}
 	StructurePreference struct {
 		// Workout structure generally follows these patterns
 		// First standing, then ground
-		MinStandingRatio float64
-		MaxStandingRatio float64
-		MinGroundRatio   float64
-		MaxGroundRatio   float64
+		MinStandingRatio float64 `json:"minStandingRatio"`
+		MaxStandingRatio float64 `json:"maxStandingRatio"`
+		MinGroundRatio   float64 `json:"minGroundRatio"`
+		MaxGroundRatio   float64 `json:"maxGroundRatio"`
 		// First low/medium warmup, then high effort movements, then low/medium cooldown
-		MinWarmupRatio     float64
-		MaxWarmupRatio     float64
-		MinHighEffortRatio float64
-		MaxHighEffortRatio float64
-		MinCooldownRatio   float64
-		MaxCooldownRatio   float64
+		MinWarmupRatio     float64 `json:"minWarmupRatio"`
+		MaxWarmupRatio     float64 `json:"maxWarmupRatio"`
+		MinHighEffortRatio float64 `json:"minHighEffortRatio"`
+		MaxHighEffortRatio float64 `json:"maxHighEffortRatio"`
+		MinCooldownRatio   float64 `json:"minCooldownRatio"`
+		MaxCooldownRatio   float64 `json:"maxCooldownRatio"`
 	}
 	EffortPreference struct {
 		// Soft limit to workout duration
-		MaxDuration time.Duration
+		MaxDuration time.Duration `json:"maxDuration"`
 		// DurationMultiplier is a multiplier applied to movement durations
-		DurationMultiplier float32
+		DurationMultiplier float32 `json:"durationMultiplier"`
 		// RepMultiplier is a multiplier applied to rep counts
-		RepMultiplier float32
+		RepMultiplier float32 `json:"repMultiplier"`
 	}
 	OtherPreference struct {
-		PreferredModality *Modality
-		PreferredPosition *Position
-		RequirementFree   bool
+		PreferredModality *Modality `json:"preferredModality"`
+		PreferredPosition *Position `json:"preferredPosition"`
+		// RequirementFree only allows movements that need no equipment.
+		RequirementFree bool `json:"requirementFree"`
+		// Equipment the user owns, nil means no restriction.
+		Equipment []Requirement `json:"equipment"`
 	}
 )
 
-func (movement Movement) estimateDuration() time.Duration {
+// EstimateDuration is how long the movement takes including rests between
+// reps, its calibrated Estimate if it has one.
+func (movement Movement) EstimateDuration() time.Duration {
+	if movement.Estimate > 0 {
+		return movement.Estimate
+	}
+	return movement.baseDuration()
+}
+
+// baseDuration is the uncalibrated estimate, 2 seconds of rest per rep.
+func (movement Movement) baseDuration() time.Duration {
 	durationPerRepWithRests := movement.Duration + (time.Second * 2)
 	return durationPerRepWithRests * time.Duration(movement.Reps) * max(1, time.Duration(movement.IterationsPerRep))
 }
 
-func MakeWorkout() Workout {
-	loadMovementBank()
-	// TODO set workout preferences based on user's experience
-	return Workout{Movements: movementSelection(defaultWorkoutPreferences()), Done: 0}
-}
-
-func defaultWorkoutPreferences() WorkoutPreferences {
-	return WorkoutPreferences{
-		Structure: StructurePreference{
-			MinStandingRatio: DefaultMinStandingRatio, MaxStandingRatio: DefaultMaxStandingRatio,
-			MinGroundRatio: DefaultMinGroundRatio, MaxGroundRatio: DefaultMaxGroundRatio,
-			MinWarmupRatio: DefaultMinWarmupRatio, MinHighEffortRatio: DefaultMinHighEffortRatio,
-			MinCooldownRatio: DefaultMinCooldownRatio},
-		Effort: EffortPreference{MaxDuration: BeginningWorkoutDuration, DurationMultiplier: 1, RepMultiplier: 1},
-	}
-}
-
-func movementSelection(workoutPreferences WorkoutPreferences) []Movement {
-	selection := []Movement{}
-	workoutDurations := getWorkoutDurations(workoutPreferences)
-	warmupDuration, highEffortDuration := workoutDurations[0], workoutDurations[1]
-	standingDuration := workoutDurations[3]
-	currentDuration := time.Duration(0)
-	for currentDuration < BeginningWorkoutDuration {
-		efforts := effortsForPhase(getEffortPhase(currentDuration, warmupDuration, highEffortDuration))
-		position := getPositionPhase(currentDuration, standingDuration)
-		options := queryMovements(position, efforts)
-		var thisMovement Movement
-		if len(options) == 0 {
-			fmt.Printf("Found no movement options for effort: %s position: %s\n", efforts, position)
-			options = queryMovements(position, allEfforts)
+// requirementsMet reports whether the user has everything the movement needs.
+func (movement Movement) requirementsMet(other OtherPreference) bool {
+	if other.RequirementFree {
+		return len(movement.Requirement) == 0
+	}
+	if other.Equipment == nil {
+		return true
+	}
+	for _, requirement := range movement.Requirement {
+		if !containsRequirement(other.Equipment, requirement) {
+			return false
+		}
+	}
+	return true
+}
+
+// MakeWorkout generates a workout for the given preferences with a random
+// seed.
+func MakeWorkout(preferences WorkoutPreferences) (Workout, error) {
+	return MakeWorkoutWithOptions(WorkoutOptions{Preferences: preferences, Seed: rand.Int63()})
+}
+
+// MakeWorkoutWithOptions generates a workout deterministically from the
+// options, including their seed.
+func MakeWorkoutWithOptions(options WorkoutOptions) (Workout, error) {
+	if _, err := currentBank(); err != nil {
+		return Workout{}, err
+	}
+	if options.Scheduled != nil {
+		return options.Scheduled.workout(options)
+	}
+	rng := rand.New(rand.NewSource(options.Seed))
+	if options.Format != "" && options.Format != Sequential {
+		movements, blocks, diagnostics, err := makeStructuredWorkout(rng, options)
+		if err != nil {
+			return Workout{}, err
+		}
+		workout := Workout{Movements: movements, Format: options.Format, Blocks: blocks,
+			Slots: diagnostics.movementSlots(), Seed: options.Seed}
+		if options.Debug {
+			workout.Diagnostics = diagnostics
+		}
+		return workout, nil
+	}
+	movements, diagnostics, err := solve(rng, options)
+	if err != nil {
+		return Workout{}, err
+	}
+	workout := Workout{Movements: movements, Done: 0, Format: Sequential,
+		Slots: diagnostics.movementSlots(), Seed: options.Seed}
+	if options.Debug {
+		workout.Diagnostics = diagnostics
+	}
+	return workout, nil
+}
+
+// movementSelection picks a sequential workout's movements with the solver.
+func movementSelection(rng *rand.Rand, workoutOptions WorkoutOptions) ([]Movement, error) {
+	movements, _, err := solve(rng, workoutOptions)
+	return movements, err
+}
+
+// ruleOut drops the candidates that score zero against the movements
+// already chosen, unless that would leave none.
+func (options WorkoutOptions) ruleOut(candidates []Movement, chosen []Movement) []Movement {
+	out := []Movement{}
+	for _, movement := range candidates {
+		if options.score(movement, chosen) > 0 {
+			out = append(out, movement)
+		}
+	}
+	if len(out) == 0 {
+		return candidates
+	}
+	return out
+}
+
+// choose picks one of the candidates in proportion to their scores against
+// the movements already chosen. If they all score the same, or all zero, it
+// picks uniformly.
+func choose(rng *rand.Rand, candidates []Movement, chosen []Movement, options WorkoutOptions) Movement {
+	scores := make([]float64, len(candidates))
+	total, uniform := 0.0, true
+	for i, movement := range candidates {
+		scores[i] = options.score(movement, chosen)
+		total += scores[i]
+		uniform = uniform && scores[i] == scores[0]
+	}
+	if uniform || total == 0 {
+		return candidates[rng.Intn(len(candidates))]
+	}
+	r := rng.Float64() * total
+	for i, movement := range candidates {
+		if r -= scores[i]; r < 0 {
+			return movement
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// maxDuration falls back to BeginningWorkoutDuration when unset.
+func (preference EffortPreference) maxDuration() time.Duration {
+	if preference.MaxDuration <= 0 {
+		return BeginningWorkoutDuration
+	}
+	return preference.MaxDuration
+}
+
+// scale applies the rep and duration multipliers to a movement, never
+// dropping below a single rep or a second per rep.
+func (preference EffortPreference) scale(movement Movement) Movement {
+	if preference.RepMultiplier > 0 {
+		movement.Reps = max(1, int(math.Round(float64(movement.Reps)*float64(preference.RepMultiplier))))
+		if movement.SwitchSides && movement.Reps%2 == 1 {
+			// Keep an even number of reps so both sides get the same work.
+			movement.Reps++
 		}
-		thisMovement = options[rand.Intn(len(options))]
-		selection = append(selection, thisMovement)
-		estimatedRestPerMovement := time.Second * 2
-		currentDuration += thisMovement.estimateDuration() + estimatedRestPerMovement
 	}
-	return selection
+	if preference.DurationMultiplier > 0 {
+		scaled := time.Duration(float64(movement.Duration) * float64(preference.DurationMultiplier))
+		movement.Duration = max(time.Second, scaled.Round(time.Second))
+	}
+	return movement
+}
+
+func (preference EffortPreference) scaleAll(movements []Movement) []Movement {
+	out := make([]Movement, len(movements))
+	for i, movement := range movements {
+		out[i] = preference.scale(movement)
+	}
+	return out
+}
+
+// fitWithin returns the movements that can be completed in the remaining time.
+func fitWithin(movements []Movement, remaining time.Duration) []Movement {
+	out := []Movement{}
+	for _, movement := range movements {
+		if movement.EstimateDuration()+EstimatedRestPerMovement <= remaining {
+			out = append(out, movement)
+		}
+	}
+	return out
 }
 
 func effortsForPhase(phase WorkoutEffortPhase) []Effort {
@@ -189,13 +373,13 @@ func getPositionPhase(currentDuration, standingDuration time.Duration) Position
 }
 
 // getWorkoutRatios chooses a warmup/cooldown and standing/ground ratio.
-func getWorkoutDurations(preferences WorkoutPreferences) []time.Duration {
-	warmupRatio := preferences.Structure.MinWarmupRatio + ((preferences.Structure.MaxWarmupRatio - preferences.Structure.MinWarmupRatio) * rand.Float64())
-	highEffortRatio := preferences.Structure.MinHighEffortRatio + ((preferences.Structure.MaxHighEffortRatio - preferences.Structure.MinHighEffortRatio) * rand.Float64())
+func getWorkoutDurations(rng *rand.Rand, preferences WorkoutPreferences) []time.Duration {
+	warmupRatio := preferences.Structure.MinWarmupRatio + ((preferences.Structure.MaxWarmupRatio - preferences.Structure.MinWarmupRatio) * rng.Float64())
+	highEffortRatio := preferences.Structure.MinHighEffortRatio + ((preferences.Structure.MaxHighEffortRatio - preferences.Structure.MinHighEffortRatio) * rng.Float64())
 	coolDownRatio := 1 - warmupRatio - highEffortRatio
-	standingRatio := preferences.Structure.MinStandingRatio + ((preferences.Structure.MaxStandingRatio - preferences.Structure.MinStandingRatio) * rand.Float64())
+	standingRatio := preferences.Structure.MinStandingRatio + ((preferences.Structure.MaxStandingRatio - preferences.Structure.MinStandingRatio) * rng.Float64())
 	groundRatio := 1 - standingRatio
-	workoutDurationMs := BeginningWorkoutDuration.Milliseconds()
+	workoutDurationMs := preferences.Effort.maxDuration().Milliseconds()
 	ratios := []float64{warmupRatio, highEffortRatio, coolDownRatio, standingRatio, groundRatio}
 	durations := make([]time.Duration, len(ratios))
 	for i, ratio := range ratios {
@@ -213,22 +397,80 @@ func contains(efforts []Effort, effort Effort) bool {
 	return false
 }
 
-func queryMovements(position Position, efforts []Effort) []Movement {
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRequirement(requirements []Requirement, requirement Requirement) bool {
+	for _, r := range requirements {
+		if r == requirement {
+			return true
+		}
+	}
+	return false
+}
+
+func targetsAnyFocus(movement Movement, focus []Focus) bool {
+	for _, f := range movement.Focus {
+		if containsFocus(focus, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByFocus restricts options to movements targeting one of the focus
+// areas. If fewer than MinFocusOptions match, the matches are instead
+// weighted double within the full set of options.
+func filterByFocus(options []Movement, focus []Focus) []Movement {
+	if len(focus) == 0 {
+		return options
+	}
+	matched := []Movement{}
+	for _, movement := range options {
+		if targetsAnyFocus(movement, focus) {
+			matched = append(matched, movement)
+		}
+	}
+	if len(matched) >= MinFocusOptions {
+		return matched
+	}
+	return append(append([]Movement{}, options...), matched...)
+}
+
+// queryMovements returns the movements for a slot the user can do. Mastered
+// movements are replaced by their progression, and left out if it doesn't
+// fit the slot's efforts. Those conflicting with the user's profile are
+// replaced by a regression in the same position, or left out if there is
+// none.
+func queryMovements(position Position, efforts []Effort, options WorkoutOptions) []Movement {
 	out := []Movement{}
-	for _, movement := range movementBank {
-		if contains(efforts, movement.Effort) && movement.Position == position {
+	seen := map[string]bool{}
+	bank, _ := currentBank()
+	for _, candidate := range bank {
+		if !contains(efforts, candidate.Effort) || candidate.Position != position || !options.available(candidate) {
+			continue
+		}
+		movement := options.advance(bank, candidate, map[string]bool{})
+		if !contains(efforts, movement.Effort) {
+			continue
+		}
+		movement, ok := options.Profile.regress(bank, movement)
+		if ok && movement.Position == position && options.available(movement) && !seen[movement.Name] {
+			seen[movement.Name] = true
 			out = append(out, movement)
 		}
 	}
 	return out
 }
 
-func loadMovementBank() {
-	err := json.Unmarshal(static.MovementsFS, &movementBank)
-	if err != nil {
-		fmt.Println("ERROR:", err)
-	}
-	for i, m := range movementBank {
-		movementBank[i].Duration = m.Duration * time.Second
-	}
+// available reports whether the movement may be chosen at all.
+func (options WorkoutOptions) available(movement Movement) bool {
+	return !movement.Retired && movement.requirementsMet(options.Preferences.Other) &&
+		!containsName(options.Blocked, movement.Name)
 }
@@ -1,25 +1,242 @@
 package model
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ekotlikoff/gofit/internal/static"
 )
 
+var (
+	update   = flag.Bool("update", false, "update golden files")
+	testSeed = flag.Int64("seed", 1, "seed for the randomized tests, 0 for a random one")
+)
+
+func mustLoadMovementBank(t *testing.T) {
+	if err := loadMovementBank(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustSelect(t *testing.T, preferences WorkoutPreferences) []Movement {
+	selection, err := movementSelection(newTestRand(t), WorkoutOptions{Preferences: preferences})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return selection
+}
+
+// newTestRand returns a generator seeded from -seed, logging the seed so a
+// failure with -seed=0 can be reproduced.
+func newTestRand(t *testing.T) *rand.Rand {
+	seed := *testSeed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	t.Logf("seed %d", seed)
+	return rand.New(rand.NewSource(seed))
+}
+
 func TestAllMovementsHaveImages(t *testing.T) {
-	loadMovementBank()
+	mustLoadMovementBank(t)
 	for _, m := range movementBank {
-		iterations := []string{"active"}
-		if m.IterationNames != nil {
-			iterations = m.IterationNames
-		}
-		iterations = append(iterations, "rest")
-		for _, i := range iterations {
-			imagePath := fmt.Sprintf("webpage/movement_images/%s/%s.png", m.Name, i)
-			if _, err := static.WebpageStaticFS.Open(imagePath); err != nil {
+		for _, imagePath := range m.ImagePaths() {
+			if _, err := static.WebpageStaticFS.Open("webpage/" + imagePath); err != nil {
 				t.Errorf("%s is missing its image %s", m.Name, imagePath)
 			}
 		}
 	}
 }
+
+func TestFilterByFocus(t *testing.T) {
+	options := []Movement{
+		{Name: "squat", Focus: []Focus{Hip, Knee}},
+		{Name: "wall slides", Focus: []Focus{Shoulder}},
+		{Name: "scapular clocks", Focus: []Focus{Shoulder}},
+	}
+	if got := filterByFocus(options, nil); len(got) != len(options) {
+		t.Errorf("expected no filtering without a focus, got %d options", len(got))
+	}
+	got := filterByFocus(options, []Focus{Shoulder})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 shoulder options, got %d", len(got))
+	}
+	for _, m := range got {
+		if !targetsAnyFocus(m, []Focus{Shoulder}) {
+			t.Errorf("%s does not target shoulder", m.Name)
+		}
+	}
+	got = filterByFocus(options, []Focus{Hip})
+	if len(got) != len(options)+1 {
+		t.Errorf("expected fallback to all options with hip weighted, got %d options", len(got))
+	}
+}
+
+func TestMovementSelectionHonorsFocus(t *testing.T) {
+	mustLoadMovementBank(t)
+	preferences := DefaultWorkoutPreferences()
+	preferences.Focus = []Focus{Shoulder}
+	for _, m := range mustSelect(t, preferences) {
+		if m.Position == Standing && !targetsAnyFocus(m, preferences.Focus) {
+			t.Errorf("standing movement %s does not target %s", m.Name, Shoulder)
+		}
+	}
+}
+
+func TestRequirementsLoad(t *testing.T) {
+	mustLoadMovementBank(t)
+	for _, m := range movementBank {
+		if m.Name == "sit to stand" {
+			if len(m.Requirement) != 1 || m.Requirement[0] != Chair {
+				t.Errorf("expected sit to stand to require a chair, got %v", m.Requirement)
+			}
+			return
+		}
+	}
+	t.Error("sit to stand is missing from the movement bank")
+}
+
+func TestMovementSelectionHonorsEquipment(t *testing.T) {
+	mustLoadMovementBank(t)
+	preferences := DefaultWorkoutPreferences()
+	preferences.Other.RequirementFree = true
+	for _, m := range mustSelect(t, preferences) {
+		if len(m.Requirement) != 0 {
+			t.Errorf("%s requires %v in a requirement free workout", m.Name, m.Requirement)
+		}
+	}
+	preferences.Other = OtherPreference{Equipment: []Requirement{Mat}}
+	for _, m := range mustSelect(t, preferences) {
+		if !m.requirementsMet(preferences.Other) {
+			t.Errorf("%s requires %v but only a mat is available", m.Name, m.Requirement)
+		}
+	}
+}
+
+func TestEffortPreferenceScale(t *testing.T) {
+	preference := EffortPreference{DurationMultiplier: 1.5, RepMultiplier: 0.5}
+	m := preference.scale(Movement{Name: "lunge", Reps: 10, Duration: 4 * time.Second, SwitchSides: true})
+	if m.Reps != 6 {
+		t.Errorf("expected 10 reps halved and rounded up to an even 6, got %d", m.Reps)
+	}
+	if m.Duration != 6*time.Second {
+		t.Errorf("expected 6s duration, got %s", m.Duration)
+	}
+	m = preference.scale(Movement{Name: "squat", Reps: 1, Duration: time.Second})
+	if m.Reps != 1 {
+		t.Errorf("expected reps to be clamped to 1, got %d", m.Reps)
+	}
+}
+
+func TestMovementSelectionHonorsMaxDuration(t *testing.T) {
+	mustLoadMovementBank(t)
+	for _, minutes := range []time.Duration{5, 15, 30} {
+		preferences := DefaultWorkoutPreferences()
+		preferences.Effort.MaxDuration = minutes * time.Minute
+		total := time.Duration(0)
+		for _, m := range mustSelect(t, preferences) {
+			total += m.EstimateDuration() + EstimatedRestPerMovement
+		}
+		if total > preferences.Effort.MaxDuration {
+			t.Errorf("%s workout overshot to %s", preferences.Effort.MaxDuration, total)
+		}
+		if total < preferences.Effort.MaxDuration/2 {
+			t.Errorf("%s workout only lasts %s", preferences.Effort.MaxDuration, total)
+		}
+	}
+}
+
+func TestMakeWorkoutWithOptionsIsDeterministic(t *testing.T) {
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 7}
+	first, err := MakeWorkoutWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := MakeWorkoutWithOptions(options)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected identical workouts for the same seed")
+	}
+	if first.Seed != options.Seed {
+		t.Errorf("expected seed %d to be recorded, got %d", options.Seed, first.Seed)
+	}
+	a, _ := MakeWorkout(options.Preferences)
+	b, _ := MakeWorkout(options.Preferences)
+	if a.Seed == b.Seed {
+		t.Errorf("expected MakeWorkout to pick a new seed each time")
+	}
+}
+
+// TestMakeWorkoutGolden pins the generator's output, run with -update after
+// intentionally changing it.
+func TestMakeWorkoutGolden(t *testing.T) {
+	workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.MarshalIndent(workout, "", "    ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "workout_seed_42.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("workout for seed 42 differs from %s:\n%s", golden, got)
+	}
+}
+
+func TestMovementSelectionUnsatisfiable(t *testing.T) {
+	movementBank = []Movement{{Name: "squat", Reps: 2, Duration: time.Second, Position: Standing, Effort: High}}
+	defer mustLoadMovementBank(t)
+	_, err := movementSelection(newTestRand(t), WorkoutOptions{Preferences: DefaultWorkoutPreferences()})
+	var unsatisfiable *UnsatisfiableError
+	if !errors.As(err, &unsatisfiable) || unsatisfiable.Position != Ground {
+		t.Errorf("expected no ground movements to be unsatisfiable, got %v", err)
+	}
+}
+
+func TestMovementSelectionExcludesBlocked(t *testing.T) {
+	mustLoadMovementBank(t)
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(),
+		Blocked: []string{"lunge", "side lunge", "squat"}}
+	rng := newTestRand(t)
+	for i := 0; i < 20; i++ {
+		selection, err := movementSelection(rng, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range selection {
+			if containsName(options.Blocked, m.Name) {
+				t.Fatalf("blocked movement %s was selected", m.Name)
+			}
+		}
+	}
+}
+
+func TestChooseFavorsFavorites(t *testing.T) {
+	candidates := []Movement{{Name: "squat"}, {Name: "lunge"}}
+	options := WorkoutOptions{Favorites: []string{"lunge"}}
+	rng := newTestRand(t)
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[choose(rng, candidates, nil, options).Name]++
+	}
+	// lunge has FavoriteWeight times squat's weight, so about 750 of 1000.
+	if counts["lunge"] < 650 || counts["lunge"] > 850 {
+		t.Errorf("expected lunge to be picked about 750 times, got %d", counts["lunge"])
+	}
+}
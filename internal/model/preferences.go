@@ -0,0 +1,125 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxWorkoutDuration is the longest workout a user may ask for.
+const MaxWorkoutDuration = 2 * time.Hour
+
+var (
+	allPositions    = []Position{Standing, Ground}
+	allModalities   = []Modality{Strength, Flexibility, Mobility}
+	allFocus        = []Focus{Hip, Back, Knee, Shoulder, Wrist, Ankle}
+	allRequirements = []Requirement{Mat, Chair, Band}
+)
+
+// DefaultWorkoutPreferences are used for users who have not set their own.
+func DefaultWorkoutPreferences() WorkoutPreferences {
+	return WorkoutPreferences{
+		Structure: StructurePreference{
+			MinStandingRatio: DefaultMinStandingRatio, MaxStandingRatio: DefaultMaxStandingRatio,
+			MinGroundRatio: DefaultMinGroundRatio, MaxGroundRatio: DefaultMaxGroundRatio,
+			MinWarmupRatio: DefaultMinWarmupRatio, MaxWarmupRatio: DefaultMaxWarmupRatio,
+			MinHighEffortRatio: DefaultMinHighEffortRatio, MaxHighEffortRatio: DefaultMaxHighEffortRatio,
+			MinCooldownRatio: DefaultMinCooldownRatio, MaxCooldownRatio: DefaultMaxCooldownRatio},
+		Effort: EffortPreference{MaxDuration: BeginningWorkoutDuration, DurationMultiplier: 1, RepMultiplier: 1},
+	}
+}
+
+// Validate checks that the preferences describe a workout that can be built.
+func (preferences WorkoutPreferences) Validate() error {
+	if err := preferences.Structure.validate(); err != nil {
+		return err
+	}
+	if err := preferences.Effort.validate(); err != nil {
+		return err
+	}
+	for _, focus := range preferences.Focus {
+		if !containsFocus(allFocus, focus) {
+			return fmt.Errorf("unknown focus %q", focus)
+		}
+	}
+	return preferences.Other.validate()
+}
+
+func (structure StructurePreference) validate() error {
+	ranges := []struct {
+		name     string
+		min, max float64
+	}{
+		{"standing", structure.MinStandingRatio, structure.MaxStandingRatio},
+		{"ground", structure.MinGroundRatio, structure.MaxGroundRatio},
+		{"warmup", structure.MinWarmupRatio, structure.MaxWarmupRatio},
+		{"high effort", structure.MinHighEffortRatio, structure.MaxHighEffortRatio},
+		{"cooldown", structure.MinCooldownRatio, structure.MaxCooldownRatio},
+	}
+	for _, r := range ranges {
+		if r.min < 0 || r.max > 1 {
+			return fmt.Errorf("%s ratio must be between 0 and 1", r.name)
+		} else if r.min > r.max {
+			return fmt.Errorf("min %s ratio %.2f exceeds max %.2f", r.name, r.min, r.max)
+		}
+	}
+	if structure.MinStandingRatio+structure.MinGroundRatio > 1 {
+		return fmt.Errorf("min standing and ground ratios sum to more than 1")
+	}
+	if structure.MinWarmupRatio+structure.MinHighEffortRatio+structure.MinCooldownRatio > 1 {
+		return fmt.Errorf("min warmup, high effort and cooldown ratios sum to more than 1")
+	}
+	return nil
+}
+
+func (effort EffortPreference) validate() error {
+	if effort.MaxDuration <= 0 || effort.MaxDuration > MaxWorkoutDuration {
+		return fmt.Errorf("max duration must be between 0 and %s", MaxWorkoutDuration)
+	} else if effort.DurationMultiplier <= 0 {
+		return fmt.Errorf("duration multiplier must be positive")
+	} else if effort.RepMultiplier <= 0 {
+		return fmt.Errorf("rep multiplier must be positive")
+	}
+	return nil
+}
+
+func (other OtherPreference) validate() error {
+	if other.PreferredModality != nil && !containsModality(allModalities, *other.PreferredModality) {
+		return fmt.Errorf("unknown modality %q", *other.PreferredModality)
+	}
+	if other.PreferredPosition != nil && !containsPosition(allPositions, *other.PreferredPosition) {
+		return fmt.Errorf("unknown position %q", *other.PreferredPosition)
+	}
+	for _, requirement := range other.Equipment {
+		if !containsRequirement(allRequirements, requirement) {
+			return fmt.Errorf("unknown equipment %q", requirement)
+		}
+	}
+	return nil
+}
+
+func containsFocus(focus []Focus, f Focus) bool {
+	for _, candidate := range focus {
+		if candidate == f {
+			return true
+		}
+	}
+	return false
+}
+
+func containsModality(modalities []Modality, modality Modality) bool {
+	for _, m := range modalities {
+		if m == modality {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPosition(positions []Position, position Position) bool {
+	for _, p := range positions {
+		if p == position {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultWorkoutPreferencesAreValid(t *testing.T) {
+	if err := DefaultWorkoutPreferences().Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateRejectsBadPreferences(t *testing.T) {
+	unknown := Modality("yoga")
+	cases := map[string]func(*WorkoutPreferences){
+		"min above max":      func(p *WorkoutPreferences) { p.Structure.MinWarmupRatio = 0.5; p.Structure.MaxWarmupRatio = 0.4 },
+		"ratio above one":    func(p *WorkoutPreferences) { p.Structure.MaxGroundRatio = 1.5 },
+		"negative ratio":     func(p *WorkoutPreferences) { p.Structure.MinStandingRatio = -0.1 },
+		"mins exceed whole":  func(p *WorkoutPreferences) { p.Structure.MinStandingRatio = 0.6; p.Structure.MinGroundRatio = 0.6 },
+		"zero duration":      func(p *WorkoutPreferences) { p.Effort.MaxDuration = 0 },
+		"too long":           func(p *WorkoutPreferences) { p.Effort.MaxDuration = 3 * time.Hour },
+		"zero rep scale":     func(p *WorkoutPreferences) { p.Effort.RepMultiplier = 0 },
+		"unknown focus":      func(p *WorkoutPreferences) { p.Focus = []Focus{"elbow"} },
+		"unknown equipment":  func(p *WorkoutPreferences) { p.Other.Equipment = []Requirement{"kettlebell"} },
+		"unknown modality":   func(p *WorkoutPreferences) { p.Other.PreferredModality = &unknown },
+		"negative rep scale": func(p *WorkoutPreferences) { p.Effort.RepMultiplier = -1 },
+	}
+	for name, mutate := range cases {
+		preferences := DefaultWorkoutPreferences()
+		mutate(&preferences)
+		if preferences.Validate() == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package model
+
+import "fmt"
+
+const (
+	LoadedKneeFlexion  = Contraindication("loaded knee flexion")
+	WristWeightBearing = Contraindication("wrist weight bearing")
+	// GroundWork is implied by the ground position rather than tagged.
+	GroundWork = Contraindication("ground work")
+)
+
+var allContraindications = []Contraindication{LoadedKneeFlexion, WristWeightBearing, GroundWork}
+
+type (
+	// Contraindication is a kind of load some users must avoid.
+	Contraindication string
+	// Profile describes a user's physical limitations.
+	Profile struct {
+		// Limitations the user's workouts must avoid.
+		Limitations []Contraindication `json:"limitations"`
+	}
+)
+
+// Validate checks the profile only names known contraindications.
+func (profile Profile) Validate() error {
+	for _, limitation := range profile.Limitations {
+		if !containsContraindication(allContraindications, limitation) {
+			return fmt.Errorf("limitation %q is not one of %s", limitation, allContraindications)
+		}
+	}
+	return nil
+}
+
+// conflicts reports whether the movement loads one of the user's
+// limitations.
+func (profile Profile) conflicts(movement Movement) bool {
+	for _, limitation := range profile.Limitations {
+		if containsContraindication(movement.Contraindications, limitation) ||
+			(limitation == GroundWork && movement.Position == Ground) {
+			return true
+		}
+	}
+	return false
+}
+
+// position maps a phase's position to one the user can work in, so a user
+// who can't get down on the floor stays standing through the ground phase.
+func (profile Profile) position(position Position) Position {
+	if position == Ground && containsContraindication(profile.Limitations, GroundWork) {
+		return Standing
+	}
+	return position
+}
+
+// regress follows the movement's regressions until one doesn't conflict with
+// the profile, reporting false if none is left in the bank.
+func (profile Profile) regress(bank []Movement, movement Movement) (Movement, bool) {
+	for i := 0; i <= len(bank); i++ {
+		if !profile.conflicts(movement) {
+			return movement, true
+		}
+		var found bool
+		if movement, found = findMovement(bank, movement.Regression); !found {
+			return Movement{}, false
+		}
+	}
+	// The regressions form a cycle.
+	return Movement{}, false
+}
+
+func findMovement(bank []Movement, name string) (Movement, bool) {
+	for _, movement := range bank {
+		if name != "" && movement.Name == name {
+			return movement, true
+		}
+	}
+	return Movement{}, false
+}
+
+func containsContraindication(contraindications []Contraindication, contraindication Contraindication) bool {
+	for _, c := range contraindications {
+		if c == contraindication {
+			return true
+		}
+	}
+	return false
+}
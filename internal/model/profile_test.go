@@ -0,0 +1,59 @@
+package model
+
+import "testing"
+
+func TestProfileWithoutGroundWorkStaysStanding(t *testing.T) {
+	mustLoadMovementBank(t)
+	profile := Profile{Limitations: []Contraindication{GroundWork}}
+	for _, format := range []Format{Sequential, Circuit} {
+		workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(),
+			Seed: newTestRand(t).Int63(), Format: format, Profile: profile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(workout.Movements) == 0 {
+			t.Fatalf("%s: empty workout", format)
+		}
+		for _, movement := range workout.Movements {
+			if movement.Position == Ground {
+				t.Errorf("%s: selected ground movement %s", format, movement.Name)
+			}
+		}
+	}
+}
+
+func TestProfileRegressesConflictingMovements(t *testing.T) {
+	mustLoadMovementBank(t)
+	profile := Profile{Limitations: []Contraindication{LoadedKneeFlexion}}
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Profile: profile}
+	candidates := queryMovements(Standing, []Effort{Medium, High}, options)
+	var regressed bool
+	for _, movement := range candidates {
+		if profile.conflicts(movement) {
+			t.Errorf("%s conflicts with %v", movement.Name, profile.Limitations)
+		}
+		regressed = regressed || movement.Name == "hip hinge"
+	}
+	if !regressed {
+		t.Error("expected squat to regress to hip hinge")
+	}
+}
+
+func TestRegressFollowsChain(t *testing.T) {
+	bank := []Movement{
+		{Name: "pistol", Contraindications: []Contraindication{LoadedKneeFlexion}, Regression: "squat"},
+		{Name: "squat", Contraindications: []Contraindication{LoadedKneeFlexion}, Regression: "hinge"},
+		{Name: "hinge"},
+		{Name: "loop", Contraindications: []Contraindication{LoadedKneeFlexion}, Regression: "loop"},
+	}
+	profile := Profile{Limitations: []Contraindication{LoadedKneeFlexion}}
+	if movement, ok := profile.regress(bank, bank[0]); !ok || movement.Name != "hinge" {
+		t.Errorf("regressed pistol to %q, %v", movement.Name, ok)
+	}
+	if _, ok := profile.regress(bank, bank[3]); ok {
+		t.Error("expected a regression cycle to be left out")
+	}
+	if err := (Profile{Limitations: []Contraindication{"no jumping"}}).Validate(); err == nil {
+		t.Error("expected an unknown limitation to be rejected")
+	}
+}
@@ -0,0 +1,208 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DateLayout is how program start dates are written.
+const DateLayout = "2006-01-02"
+
+var (
+	// ErrUnknownProgram is returned when enrolling in a program that
+	// doesn't exist.
+	ErrUnknownProgram = errors.New("unknown program")
+	// ErrProgramNotStarted is returned scheduling a day before the start.
+	ErrProgramNotStarted = errors.New("the program hasn't started")
+	// ErrProgramOver is returned scheduling a day after the last week.
+	ErrProgramOver = errors.New("the program is over")
+)
+
+type (
+	// ProgramDay is the session a program schedules on a weekday.
+	ProgramDay struct {
+		// Rest days have no workout.
+		Rest   bool   `json:"rest,omitempty"`
+		Format Format `json:"format,omitempty"`
+		// Focus replaces the user's focus preference for the session.
+		Focus []Focus `json:"focus,omitempty"`
+	}
+	// Program is a multi-week plan. Each week follows the same days,
+	// growing longer by WeeklyIncrease, except every DeloadEvery'th week,
+	// which eases off to DeloadRatio of the duration.
+	Program struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Weeks       int    `json:"weeks"`
+		// Days are the sessions of each week by weekday, Sunday first.
+		Days           [7]ProgramDay `json:"days"`
+		StartDuration  time.Duration `json:"startDuration"`
+		WeeklyIncrease time.Duration `json:"weeklyIncrease"`
+		DeloadEvery    int           `json:"deloadEvery"`
+		DeloadRatio    float64       `json:"deloadRatio"`
+	}
+	// Enrollment is a user's place in a program.
+	Enrollment struct {
+		Program string `json:"program"`
+		// Start is the date of the program's first day, in DateLayout.
+		Start string `json:"start"`
+	}
+	// ScheduledDay is the session a program schedules on a date.
+	ScheduledDay struct {
+		ProgramDay
+		Program string `json:"program"`
+		// Week of the program, starting at 1.
+		Week        int           `json:"week"`
+		Deload      bool          `json:"deload,omitempty"`
+		MaxDuration time.Duration `json:"maxDuration"`
+	}
+)
+
+var rest = ProgramDay{Rest: true}
+
+var programs = []Program{
+	{
+		Name:        "foundations",
+		Description: "Three gentle sessions a week to build a habit.",
+		Weeks:       4,
+		Days: [7]ProgramDay{rest, {Format: Sequential}, rest, {Format: Circuit},
+			rest, {Format: Sequential}, rest},
+		StartDuration: 8 * time.Minute, WeeklyIncrease: 2 * time.Minute,
+		DeloadEvery: 4, DeloadRatio: 0.6,
+	},
+	{
+		Name:        "strength",
+		Description: "Five sessions a week of structured strength work.",
+		Weeks:       8,
+		Days: [7]ProgramDay{rest,
+			{Format: Circuit, Focus: []Focus{Hip, Knee}},
+			{Format: Superset, Focus: []Focus{Shoulder, Back}},
+			rest,
+			{Format: Tabata},
+			{Format: Circuit, Focus: []Focus{Hip, Ankle}},
+			{Format: AMRAP}},
+		StartDuration: 12 * time.Minute, WeeklyIncrease: 2 * time.Minute,
+		DeloadEvery: 4, DeloadRatio: 0.6,
+	},
+	{
+		Name:        "mobility",
+		Description: "Short weekday sessions working around the joints.",
+		Weeks:       6,
+		Days: [7]ProgramDay{rest,
+			{Format: Sequential, Focus: []Focus{Hip, Back}},
+			{Format: Sequential, Focus: []Focus{Shoulder, Wrist}},
+			{Format: Sequential, Focus: []Focus{Knee, Ankle}},
+			{Format: Sequential, Focus: []Focus{Hip, Back}},
+			{Format: Sequential},
+			rest},
+		StartDuration: 10 * time.Minute, WeeklyIncrease: time.Minute,
+		DeloadEvery: 3, DeloadRatio: 0.7,
+	},
+}
+
+// Programs returns the programs users can enroll in.
+func Programs() []Program {
+	return append([]Program{}, programs...)
+}
+
+// FindProgram returns the named program.
+func FindProgram(name string) (Program, bool) {
+	for _, program := range programs {
+		if program.Name == name {
+			return program, true
+		}
+	}
+	return Program{}, false
+}
+
+// Validate checks the program schedules workouts that can be built.
+func (program Program) Validate() error {
+	if program.Weeks < 1 {
+		return fmt.Errorf("%s: weeks must be positive", program.Name)
+	}
+	if program.StartDuration <= 0 || program.duration(program.Weeks-1) > MaxWorkoutDuration {
+		return fmt.Errorf("%s: durations must be between 0 and %s", program.Name, MaxWorkoutDuration)
+	}
+	if program.DeloadEvery < 0 || program.DeloadRatio < 0 || program.DeloadRatio > 1 {
+		return fmt.Errorf("%s: deload ratio must be between 0 and 1", program.Name)
+	}
+	for weekday, day := range program.Days {
+		if !day.Rest && day.Format != "" && !ValidFormat(day.Format) {
+			return fmt.Errorf("%s: %s has unknown format %q", program.Name, time.Weekday(weekday), day.Format)
+		}
+		for _, focus := range day.Focus {
+			if !containsFocus(allFocus, focus) {
+				return fmt.Errorf("%s: %s has unknown focus %q", program.Name, time.Weekday(weekday), focus)
+			}
+		}
+	}
+	return nil
+}
+
+// duration is the undeloaded MaxDuration of a week, counting from 0.
+func (program Program) duration(week int) time.Duration {
+	return program.StartDuration + time.Duration(week)*program.WeeklyIncrease
+}
+
+// deload reports whether the week, counting from 0, is a deload week.
+func (program Program) deload(week int) bool {
+	return program.DeloadEvery > 0 && (week+1)%program.DeloadEvery == 0
+}
+
+// Validate checks the enrollment names a program and a start date.
+func (enrollment Enrollment) Validate() error {
+	if _, found := FindProgram(enrollment.Program); !found {
+		return ErrUnknownProgram
+	}
+	if _, err := time.Parse(DateLayout, enrollment.Start); err != nil {
+		return fmt.Errorf("start must be a date like %s", DateLayout)
+	}
+	return nil
+}
+
+// Schedule returns the session the enrollment's program schedules on the
+// day's date.
+func (enrollment Enrollment) Schedule(day time.Time) (ScheduledDay, error) {
+	program, found := FindProgram(enrollment.Program)
+	if !found {
+		return ScheduledDay{}, ErrUnknownProgram
+	}
+	start, err := time.Parse(DateLayout, enrollment.Start)
+	if err != nil {
+		return ScheduledDay{}, err
+	}
+	// Compare dates in UTC so daylight saving changes don't shift days.
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(date.Sub(start) / (24 * time.Hour))
+	if days < 0 {
+		return ScheduledDay{}, ErrProgramNotStarted
+	}
+	week := days / 7
+	if week >= program.Weeks {
+		return ScheduledDay{}, ErrProgramOver
+	}
+	scheduled := ScheduledDay{ProgramDay: program.Days[date.Weekday()], Program: program.Name,
+		Week: week + 1, Deload: program.deload(week), MaxDuration: program.duration(week)}
+	if scheduled.Deload {
+		scheduled.MaxDuration = time.Duration(float64(scheduled.MaxDuration) * program.DeloadRatio).Round(time.Minute)
+	}
+	return scheduled, nil
+}
+
+// workout builds the scheduled session, with the program's format,
+// duration and focus in place of the user's. Rest days have no movements.
+func (scheduled ScheduledDay) workout(options WorkoutOptions) (Workout, error) {
+	options.Scheduled = nil
+	if scheduled.Rest {
+		return Workout{Movements: []Movement{}, Format: Sequential, Seed: options.Seed, Scheduled: &scheduled}, nil
+	}
+	options.Format = scheduled.Format
+	options.Preferences.Effort.MaxDuration = scheduled.MaxDuration
+	if len(scheduled.Focus) > 0 {
+		options.Preferences.Focus = scheduled.Focus
+	}
+	workout, err := MakeWorkoutWithOptions(options)
+	workout.Scheduled = &scheduled
+	return workout, err
+}
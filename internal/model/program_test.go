@@ -0,0 +1,74 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgramsAreValid(t *testing.T) {
+	for _, program := range Programs() {
+		if err := program.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEnrollmentSchedule(t *testing.T) {
+	enrollment := Enrollment{Program: "foundations", Start: "2024-04-01"}
+	tests := []struct {
+		day      string
+		week     int
+		rest     bool
+		format   Format
+		duration time.Duration
+		deload   bool
+	}{
+		{"2024-04-01", 1, false, Sequential, 8 * time.Minute, false},
+		{"2024-04-02", 1, true, "", 8 * time.Minute, false},
+		{"2024-04-10", 2, false, Circuit, 10 * time.Minute, false},
+		{"2024-04-22", 4, false, Sequential, 8 * time.Minute, true},
+	}
+	for _, test := range tests {
+		day, _ := time.ParseInLocation(DateLayout, test.day, time.Local)
+		scheduled, err := enrollment.Schedule(day.Add(20 * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scheduled.Week != test.week || scheduled.Rest != test.rest || scheduled.Format != test.format ||
+			scheduled.MaxDuration != test.duration || scheduled.Deload != test.deload {
+			t.Errorf("%s: scheduled %+v", test.day, scheduled)
+		}
+	}
+	for day, want := range map[string]error{"2024-03-31": ErrProgramNotStarted, "2024-04-29": ErrProgramOver} {
+		date, _ := time.Parse(DateLayout, day)
+		if _, err := enrollment.Schedule(date); err != want {
+			t.Errorf("%s: expected %v, got %v", day, want, err)
+		}
+	}
+	if err := (Enrollment{Program: "couch to marathon", Start: "2024-04-01"}).Validate(); err != ErrUnknownProgram {
+		t.Errorf("expected ErrUnknownProgram, got %v", err)
+	}
+}
+
+func TestScheduledWorkout(t *testing.T) {
+	mustLoadMovementBank(t)
+	scheduled := ScheduledDay{ProgramDay: ProgramDay{Format: Circuit, Focus: []Focus{Shoulder}},
+		Program: "strength", Week: 2, MaxDuration: 14 * time.Minute}
+	workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(),
+		Seed: 3, Format: AMRAP, Scheduled: &scheduled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workout.Format != Circuit || workout.Scheduled == nil || workout.Scheduled.Week != 2 {
+		t.Errorf("expected the scheduled circuit, got %s %+v", workout.Format, workout.Scheduled)
+	}
+	if duration := sequenceDuration(workout.Movements); duration > scheduled.MaxDuration+time.Minute {
+		t.Errorf("workout takes %s, scheduled %s", duration, scheduled.MaxDuration)
+	}
+
+	scheduled.Rest = true
+	workout, err = MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Scheduled: &scheduled})
+	if err != nil || len(workout.Movements) != 0 || !workout.Scheduled.Rest {
+		t.Errorf("expected an empty rest day, got %+v %v", workout, err)
+	}
+}
@@ -0,0 +1,123 @@
+package model
+
+import "errors"
+
+// PromoteAfter is how many sessions a movement must be completed in before
+// it is replaced by its progressions.
+const PromoteAfter = 3
+
+// ErrStepBack is returned when stepping back from a movement the user wasn't
+// promoted to.
+var ErrStepBack = errors.New("not promoted to this movement")
+
+// Progress is a user's level along the bank's progressions, the chains of
+// movements linked by their Progression.
+type Progress struct {
+	// Sessions counts the sessions each movement was completed in since
+	// the user was last promoted past or stepped back to it.
+	Sessions map[string]int `json:"sessions"`
+	// Counted is the day each movement's last session was on, a day counts
+	// once however many workouts it had.
+	Counted map[string]string `json:"counted,omitempty"`
+	// Mastered movements are replaced by their progressions in workouts.
+	Mastered []string `json:"mastered"`
+}
+
+// NewProgress returns the progress of a user who hasn't done any workouts.
+func NewProgress() Progress {
+	return Progress{Sessions: map[string]int{}, Mastered: []string{}}
+}
+
+// Progressions returns the harder variant of each movement that has one in
+// the bank.
+func Progressions(bank []Movement) map[string]string {
+	progressions := map[string]string{}
+	for _, movement := range bank {
+		if progression, found := findMovement(bank, movement.Progression); found && !progression.Retired {
+			progressions[movement.Name] = progression.Name
+		}
+	}
+	return progressions
+}
+
+// Complete records a session on the day in which the named movements were
+// completed, promoting past those completed in PromoteAfter sessions. It
+// returns the newly mastered movements.
+func (progress *Progress) Complete(bank []Movement, day string, names []string) []string {
+	if progress.Sessions == nil {
+		progress.Sessions = map[string]int{}
+	}
+	if progress.Counted == nil {
+		progress.Counted = map[string]string{}
+	}
+	progressions := Progressions(bank)
+	promoted := []string{}
+	counted := map[string]bool{}
+	for _, name := range names {
+		if counted[name] || progress.Counted[name] == day || containsName(progress.Mastered, name) {
+			continue
+		}
+		counted[name] = true
+		progress.Counted[name] = day
+		progress.Sessions[name]++
+		if progress.Sessions[name] >= PromoteAfter && progressions[name] != "" {
+			delete(progress.Sessions, name)
+			progress.Mastered = append(progress.Mastered, name)
+			promoted = append(promoted, name)
+		}
+	}
+	return promoted
+}
+
+// StepBack returns the user to the movements they were promoted from to the
+// named movement.
+func (progress *Progress) StepBack(bank []Movement, name string) error {
+	steppedBack := map[string]bool{}
+	for _, movement := range bank {
+		if name != "" && movement.Progression == name && containsName(progress.Mastered, movement.Name) {
+			steppedBack[movement.Name] = true
+			delete(progress.Sessions, movement.Name)
+		}
+	}
+	if len(steppedBack) == 0 {
+		return ErrStepBack
+	}
+	mastered := []string{}
+	for _, m := range progress.Mastered {
+		if !steppedBack[m] {
+			mastered = append(mastered, m)
+		}
+	}
+	progress.Mastered = mastered
+	return nil
+}
+
+// advance replaces a mastered movement with the progression the user can do
+// in its place, itself advanced if mastered.
+func (options WorkoutOptions) advance(bank []Movement, movement Movement, visited map[string]bool) Movement {
+	if !containsName(options.Mastered, movement.Name) || visited[movement.Name] {
+		return movement
+	}
+	visited[movement.Name] = true
+	progression, found := findMovement(bank, movement.Progression)
+	if !found || progression.Position != movement.Position ||
+		!options.available(progression) || options.Profile.conflicts(progression) {
+		return movement
+	}
+	return options.advance(bank, progression, visited)
+}
+
+// leadsTo reports whether name is in the movement's chain of links, its
+// regressions or progressions.
+func leadsTo(bank []Movement, movement Movement, name string, link func(Movement) string) bool {
+	for i := 0; i < len(bank) && link(movement) != ""; i++ {
+		var found bool
+		if movement, found = findMovement(bank, link(movement)); !found {
+			return false
+		}
+		if movement.Name == name {
+			return true
+		}
+	}
+	return false
+}
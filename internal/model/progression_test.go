@@ -0,0 +1,86 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestProgressPromotesAndStepsBack(t *testing.T) {
+	mustLoadMovementBank(t)
+	progress := NewProgress()
+	day := func(session int) string { return fmt.Sprintf("2024-04-%02d", session) }
+	for session := 1; session < PromoteAfter; session++ {
+		if promoted := progress.Complete(movementBank, day(session), []string{"sit to stand", "sit to stand", "standing hamstring stretch", "hip hinge"}); len(promoted) != 0 {
+			t.Fatalf("session %d: promoted %v early", session, promoted)
+		}
+	}
+	if promoted := progress.Complete(movementBank, day(PromoteAfter-1), []string{"sit to stand"}); len(promoted) != 0 {
+		t.Fatalf("promoted %v from a second session on the same day", promoted)
+	}
+	promoted := progress.Complete(movementBank, day(PromoteAfter), []string{"sit to stand", "standing hamstring stretch", "hip hinge"})
+	if len(promoted) != 1 || promoted[0] != "sit to stand" {
+		t.Fatalf("expected a promotion past sit to stand, got %v", promoted)
+	}
+	if progress.Sessions["standing hamstring stretch"] != PromoteAfter {
+		t.Errorf("standing hamstring stretch has no progression but counted %d sessions", progress.Sessions["standing hamstring stretch"])
+	}
+	if progress.Sessions["hip hinge"] != PromoteAfter {
+		t.Errorf("hip hinge is only a regression but counted %d sessions", progress.Sessions["hip hinge"])
+	}
+	if err := progress.StepBack(movementBank, "core balance squat"); err != ErrStepBack {
+		t.Errorf("expected ErrStepBack from a movement not promoted to, got %v", err)
+	}
+	if err := progress.StepBack(movementBank, "staggered sit to stand"); err != nil || len(progress.Mastered) != 0 {
+		t.Errorf("expected to step back to sit to stand, got %v %v", err, progress.Mastered)
+	}
+}
+
+func TestQueryMovementsAdvancesMastered(t *testing.T) {
+	mustLoadMovementBank(t)
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(),
+		Mastered: []string{"sit to stand", "staggered sit to stand"}}
+	names := map[string]bool{}
+	for _, movement := range queryMovements(Standing, effortsForPhase(HighEffortPhase), options) {
+		names[movement.Name] = true
+	}
+	if names["sit to stand"] || !names["core balance squat"] {
+		t.Errorf("expected sit to stand to advance to core balance squat, got %v", names)
+	}
+	for _, movement := range queryMovements(Standing, []Effort{Medium}, options) {
+		if movement.Effort != Medium {
+			t.Errorf("advanced to %s effort %s in a medium slot", movement.Effort, movement.Name)
+		}
+	}
+	options.Mastered = append(options.Mastered, "standing hip extension")
+	for _, movement := range queryMovements(Standing, effortsForPhase(WarmupPhase), options) {
+		if movement.Effort != Low {
+			t.Errorf("advanced to %s effort %s in a warmup slot", movement.Effort, movement.Name)
+		}
+	}
+	options.Profile = Profile{Limitations: []Contraindication{LoadedKneeFlexion}}
+	for _, movement := range queryMovements(Standing, effortsForPhase(HighEffortPhase), options) {
+		if options.Profile.conflicts(movement) {
+			t.Errorf("advanced to %s despite the profile", movement.Name)
+		}
+	}
+}
+
+func TestValidateBankFindsLinkCycles(t *testing.T) {
+	images := fstest.MapFS{
+		"movement_images/a/active.png": {}, "movement_images/a/rest.png": {},
+		"movement_images/b/active.png": {}, "movement_images/b/rest.png": {},
+	}
+	for _, field := range []string{"regression", "progression"} {
+		data := []byte(`[
+			{"Name": "a", "reps": 1, "Duration": 5, "Position": "standing", "Modality": "strength",
+				"Focus": ["hip"], "SwitchSides": false, "Effort": "low", "` + field + `": "b"},
+			{"Name": "b", "reps": 1, "Duration": 5, "Position": "standing", "Modality": "strength",
+				"Focus": ["hip"], "SwitchSides": false, "Effort": "low", "` + field + `": "a"}
+		]`)
+		problems := ValidateBank(data, images)
+		if len(problems) != 2 || problems[0].Field != field {
+			t.Errorf("expected both movements' %ss to be reported, got %v", field, problems)
+		}
+	}
+}
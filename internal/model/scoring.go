@@ -0,0 +1,102 @@
+package model
+
+// RecentDays is how many days back completed movements are chosen less often
+// and count towards focus coverage.
+const RecentDays = 7
+
+type (
+	// RecentMovement is a movement the user completed DaysAgo days ago.
+	RecentMovement struct {
+		Name    string  `json:"name"`
+		Focus   []Focus `json:"focus"`
+		DaysAgo int     `json:"daysAgo"`
+	}
+	// Scorer weighs a candidate movement against those already chosen for
+	// the workout. Higher scores are likelier and zero rules it out.
+	Scorer func(movement Movement, chosen []Movement, options WorkoutOptions) float64
+)
+
+// DefaultScore combines the scorers used when the options don't set one.
+var DefaultScore = CombineScores(PreferenceScore, NoRepeatScore, RecencyScore, FocusBalanceScore)
+
+// CombineScores multiplies the scorers' scores.
+func CombineScores(scorers ...Scorer) Scorer {
+	return func(movement Movement, chosen []Movement, options WorkoutOptions) float64 {
+		score := 1.0
+		for _, scorer := range scorers {
+			score *= scorer(movement, chosen, options)
+		}
+		return score
+	}
+}
+
+// PreferenceScore weighs movements down by how often they were swapped out
+// and up if they are a favorite.
+func PreferenceScore(movement Movement, chosen []Movement, options WorkoutOptions) float64 {
+	score := 1 / float64(1+options.Swapped[movement.Name])
+	if containsName(options.Favorites, movement.Name) {
+		score *= FavoriteWeight
+	}
+	return score
+}
+
+// NoRepeatScore rules out movements already in the workout.
+func NoRepeatScore(movement Movement, chosen []Movement, options WorkoutOptions) float64 {
+	for _, c := range chosen {
+		if c.Name == movement.Name {
+			return 0
+		}
+	}
+	return 1
+}
+
+// RecencyScore weighs down movements done in the last RecentDays, those done
+// today the most.
+func RecencyScore(movement Movement, chosen []Movement, options WorkoutOptions) float64 {
+	score := 1.0
+	for _, recent := range options.Recent {
+		if recent.Name == movement.Name && recent.DaysAgo < RecentDays {
+			score = min(score, float64(1+recent.DaysAgo)/float64(1+RecentDays))
+		}
+	}
+	return score
+}
+
+// FocusBalanceScore favors movements for the focus areas covered least in
+// the last RecentDays and the workout so far.
+func FocusBalanceScore(movement Movement, chosen []Movement, options WorkoutOptions) float64 {
+	if len(movement.Focus) == 0 {
+		return 1
+	}
+	coverage := map[Focus]int{}
+	for _, recent := range options.Recent {
+		if recent.DaysAgo < RecentDays {
+			for _, focus := range recent.Focus {
+				coverage[focus]++
+			}
+		}
+	}
+	for _, c := range chosen {
+		for _, focus := range c.Focus {
+			coverage[focus]++
+		}
+	}
+	total := 0
+	for _, focus := range allFocus {
+		total += coverage[focus]
+	}
+	mine := 0
+	for _, focus := range movement.Focus {
+		mine += coverage[focus]
+	}
+	average := float64(total) / float64(len(allFocus))
+	return (1 + average) / (1 + float64(mine)/float64(len(movement.Focus)))
+}
+
+// score weighs the candidate with the options' scorer, DefaultScore if unset.
+func (options WorkoutOptions) score(movement Movement, chosen []Movement) float64 {
+	if options.Score != nil {
+		return max(0, options.Score(movement, chosen, options))
+	}
+	return DefaultScore(movement, chosen, options)
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+// chooseDistribution picks from the candidates n times, returning the share
+// of picks each got.
+func chooseDistribution(t *testing.T, candidates []Movement, options WorkoutOptions, n int) map[string]float64 {
+	t.Helper()
+	rng := newTestRand(t)
+	shares := map[string]float64{}
+	for i := 0; i < n; i++ {
+		shares[choose(rng, candidates, nil, options).Name] += 1 / float64(n)
+	}
+	t.Logf("distribution %v", shares)
+	return shares
+}
+
+// expectedShares is each candidate's share of the total score.
+func expectedShares(candidates []Movement, options WorkoutOptions) map[string]float64 {
+	total, shares := 0.0, map[string]float64{}
+	for _, movement := range candidates {
+		total += options.score(movement, nil)
+	}
+	for _, movement := range candidates {
+		shares[movement.Name] = options.score(movement, nil) / total
+	}
+	return shares
+}
+
+func assertShares(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+	for name, share := range want {
+		if math.Abs(got[name]-share) > 0.05 {
+			t.Errorf("%s picked %.2f of the time, want %.2f", name, got[name], share)
+		}
+	}
+}
+
+func TestMovementSelectionAvoidsRepeats(t *testing.T) {
+	mustLoadMovementBank(t)
+	rng := newTestRand(t)
+	for i := 0; i < 200; i++ {
+		selection, err := movementSelection(rng, WorkoutOptions{Preferences: DefaultWorkoutPreferences()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := map[string]bool{}
+		for _, movement := range selection {
+			if seen[movement.Name] {
+				t.Fatalf("%s was chosen twice", movement.Name)
+			}
+			seen[movement.Name] = true
+		}
+	}
+}
+
+func TestRecencyScoreDistribution(t *testing.T) {
+	candidates := []Movement{{Name: "squat"}, {Name: "lunge"}, {Name: "bridge"}, {Name: "clamshell"}}
+	options := WorkoutOptions{Recent: []RecentMovement{
+		{Name: "squat", DaysAgo: 0}, {Name: "lunge", DaysAgo: 3}, {Name: "bridge", DaysAgo: RecentDays},
+	}}
+	want := expectedShares(candidates, options)
+	if want["squat"] >= want["lunge"] || want["lunge"] >= want["bridge"] || want["bridge"] != want["clamshell"] {
+		t.Fatalf("expected recency to order the scores, got %v", want)
+	}
+	assertShares(t, chooseDistribution(t, candidates, options, 4000), want)
+}
+
+func TestFocusBalanceScoreDistribution(t *testing.T) {
+	candidates := []Movement{{Name: "squat", Focus: []Focus{Hip, Knee}},
+		{Name: "pull apart", Focus: []Focus{Shoulder}}, {Name: "wrist circles", Focus: []Focus{Wrist}}}
+	options := WorkoutOptions{}
+	for day := 0; day < 3; day++ {
+		options.Recent = append(options.Recent, RecentMovement{Name: "lunge", Focus: []Focus{Hip, Knee}, DaysAgo: day},
+			RecentMovement{Name: "pull apart", Focus: []Focus{Shoulder}, DaysAgo: RecentDays + day})
+	}
+	want := expectedShares(candidates, options)
+	if want["squat"] >= want["pull apart"] || want["pull apart"] != want["wrist circles"] {
+		t.Fatalf("expected the hip and knee to be weighted down, got %v", want)
+	}
+	assertShares(t, chooseDistribution(t, candidates, options, 4000), want)
+}
+
+func TestFocusBalanceEvensAWeek(t *testing.T) {
+	mustLoadMovementBank(t)
+	// spread is how unevenly a week of workouts covers the focus areas,
+	// the coverage's standard deviation over its mean.
+	spread := func(score Scorer) float64 {
+		rng := newTestRand(t)
+		total := 0.0
+		for week := 0; week < 20; week++ {
+			options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Score: score}
+			for day := 0; day < RecentDays; day++ {
+				selection, err := movementSelection(rng, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range options.Recent {
+					options.Recent[i].DaysAgo++
+				}
+				for _, movement := range selection {
+					options.Recent = append(options.Recent, RecentMovement{Name: movement.Name, Focus: movement.Focus})
+				}
+			}
+			coverage := map[Focus]float64{}
+			for _, recent := range options.Recent {
+				for _, focus := range recent.Focus {
+					coverage[focus]++
+				}
+			}
+			mean, variance := 0.0, 0.0
+			for _, focus := range allFocus {
+				mean += coverage[focus] / float64(len(allFocus))
+			}
+			for _, focus := range allFocus {
+				variance += math.Pow(coverage[focus]-mean, 2) / float64(len(allFocus))
+			}
+			total += math.Sqrt(variance) / mean
+		}
+		return total / 20
+	}
+	balanced := spread(DefaultScore)
+	unbalanced := spread(CombineScores(PreferenceScore, NoRepeatScore, RecencyScore))
+	t.Logf("focus spread %.3f balanced, %.3f without FocusBalanceScore", balanced, unbalanced)
+	if balanced >= unbalanced {
+		t.Errorf("expected balancing to even out focus coverage")
+	}
+}
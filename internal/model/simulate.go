@@ -0,0 +1,184 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+type (
+	// SimulationReport summarizes many workouts generated from the same
+	// options, to tune the structure ratios and the movement bank.
+	SimulationReport struct {
+		Runs     int `json:"runs"`
+		Failures int `json:"failures"`
+		// Violations counts the unmet constraints of failed workouts.
+		Violations map[string]int `json:"violations,omitempty"`
+		// Unsatisfiable counts workouts with a position no movement fit.
+		Unsatisfiable int `json:"unsatisfiable,omitempty"`
+		// Minutes is the estimated length of the workouts.
+		Minutes Summary `json:"minutes"`
+		// Ratios are the shares of each workout spent in each phase and
+		// position.
+		Ratios map[string]Summary `json:"ratios"`
+		// Movements are how many workouts had each movement, most first.
+		Movements []Frequency `json:"movements"`
+		// Focus are how many workouts targeted each focus area.
+		Focus []Frequency `json:"focus"`
+		// Fallbacks is how many workouts, failed or not, allowed a phase
+		// movements of any effort.
+		Fallbacks int `json:"fallbacks"`
+	}
+	// Summary describes a distribution.
+	Summary struct {
+		Min  float64 `json:"min"`
+		Mean float64 `json:"mean"`
+		P10  float64 `json:"p10"`
+		P50  float64 `json:"p50"`
+		P90  float64 `json:"p90"`
+		Max  float64 `json:"max"`
+	}
+	// Frequency is how many workouts had something and their share of the
+	// workouts generated.
+	Frequency struct {
+		Name     string  `json:"name"`
+		Workouts int     `json:"workouts"`
+		Share    float64 `json:"share"`
+	}
+)
+
+// Simulate generates runs workouts from the options, seeded from the
+// options' seed on, and reports what they were made of.
+func Simulate(options WorkoutOptions, runs int) (SimulationReport, error) {
+	if _, err := currentBank(); err != nil {
+		return SimulationReport{}, err
+	}
+	report := SimulationReport{Runs: runs, Violations: map[string]int{}, Ratios: map[string]Summary{}}
+	options.Debug = true
+	minutes := []float64{}
+	ratios := map[string][]float64{}
+	movements, focus := map[string]int{}, map[string]int{}
+	for _, f := range allFocus {
+		focus[string(f)] = 0
+	}
+	seed := options.Seed
+	for i := 0; i < runs; i++ {
+		options.Seed = seed + int64(i)
+		workout, err := MakeWorkoutWithOptions(options)
+		if err != nil {
+			var constraint *ConstraintError
+			var unsatisfiable *UnsatisfiableError
+			switch {
+			case errors.As(err, &constraint):
+				for _, v := range constraint.Violations {
+					report.Violations[v.Constraint]++
+				}
+				if constraint.Diagnostics != nil && len(constraint.Diagnostics.Fallbacks) > 0 {
+					report.Fallbacks++
+				}
+			case errors.As(err, &unsatisfiable):
+				report.Unsatisfiable++
+			default:
+				return SimulationReport{}, err
+			}
+			report.Failures++
+			continue
+		}
+		times := workoutTimes(workout)
+		total := time.Duration(0)
+		for _, position := range allPositions {
+			total += times[string(position)]
+		}
+		if total == 0 {
+			continue
+		}
+		minutes = append(minutes, total.Minutes())
+		for name, d := range times {
+			ratios[name] = append(ratios[name], float64(d)/float64(total))
+		}
+		seen := map[string]bool{}
+		for _, movement := range workout.Movements {
+			if !seen[movement.Name] {
+				seen[movement.Name] = true
+				movements[movement.Name]++
+			}
+			for _, f := range movement.Focus {
+				if !seen["focus "+string(f)] {
+					seen["focus "+string(f)] = true
+					focus[string(f)]++
+				}
+			}
+		}
+		if workout.Diagnostics != nil && len(workout.Diagnostics.Fallbacks) > 0 {
+			report.Fallbacks++
+		}
+	}
+	report.Minutes = summarize(minutes)
+	for name, values := range ratios {
+		report.Ratios[name] = summarize(values)
+	}
+	report.Movements = frequencies(movements, runs)
+	report.Focus = frequencies(focus, runs)
+	return report, nil
+}
+
+// workoutTimes is how long the workout spends in each position and, if it
+// has a slot per movement, each phase. AMRAP blocks last their time cap and
+// interval blocks their windows.
+func workoutTimes(workout Workout) map[string]time.Duration {
+	times := map[string]time.Duration{}
+	for _, position := range allPositions {
+		times[string(position)] = 0
+	}
+	var slots []Slot
+	if workout.Diagnostics != nil {
+		slots = workout.Diagnostics.Slots
+	}
+	if len(slots) == len(workout.Movements) {
+		for _, phase := range []WorkoutEffortPhase{WarmupPhase, HighEffortPhase, CooldownPhase} {
+			times[phase.String()] = 0
+		}
+	}
+	durations := stepDurations(workout.Movements, workout.Blocks)
+	for i, movement := range workout.Movements {
+		duration := durations[i]
+		times[string(movement.Position)] += duration
+		if len(slots) == len(workout.Movements) {
+			times[slots[i].Phase] += duration
+		}
+	}
+	return times
+}
+
+func summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	quantile := func(q float64) float64 {
+		return sorted[int(math.Round(q*float64(len(sorted)-1)))]
+	}
+	return Summary{Min: sorted[0], Mean: sum / float64(len(sorted)), P10: quantile(0.1),
+		P50: quantile(0.5), P90: quantile(0.9), Max: sorted[len(sorted)-1]}
+}
+
+// frequencies sorts the counts most first, then by name.
+func frequencies(counts map[string]int, runs int) []Frequency {
+	out := []Frequency{}
+	for name, count := range counts {
+		out = append(out, Frequency{Name: name, Workouts: count, Share: float64(count) / float64(runs)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Workouts != out[j].Workouts {
+			return out[i].Workouts > out[j].Workouts
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
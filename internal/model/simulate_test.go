@@ -0,0 +1,96 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	mustLoadMovementBank(t)
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 1}
+	report, err := Simulate(options, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Runs != 50 || report.Failures != 0 {
+		t.Fatalf("expected 50 workouts without failures, got %+v", report)
+	}
+	maxMinutes := options.Preferences.Effort.MaxDuration.Minutes()
+	if report.Minutes.Max > maxMinutes || report.Minutes.Min < MinFillRatio*maxMinutes {
+		t.Errorf("workouts last %.2f to %.2f minutes of %.0f", report.Minutes.Min, report.Minutes.Max, maxMinutes)
+	}
+	for _, name := range []string{"warmup", "high effort", "cooldown", "standing", "ground"} {
+		if ratio, ok := report.Ratios[name]; !ok || ratio.Mean <= 0 || ratio.Max > 1 {
+			t.Errorf("%s ratio is %+v", name, ratio)
+		}
+	}
+	if len(report.Focus) != len(allFocus) {
+		t.Errorf("expected every focus area to be reported, got %+v", report.Focus)
+	}
+	for i := 1; i < len(report.Movements); i++ {
+		if report.Movements[i].Workouts > report.Movements[i-1].Workouts {
+			t.Fatalf("movements aren't sorted most first: %+v", report.Movements)
+		}
+	}
+	again, _ := Simulate(options, 50)
+	if again.Minutes != report.Minutes {
+		t.Errorf("expected the same report for the same seed")
+	}
+}
+
+func TestSimulateStructuredFormats(t *testing.T) {
+	mustLoadMovementBank(t)
+	preferences := DefaultWorkoutPreferences()
+	preferences.Effort.MaxDuration = 20 * time.Minute
+	for format := range formatGenerators {
+		report, err := Simulate(WorkoutOptions{Preferences: preferences, Format: format, Seed: 1}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ratio, ok := report.Ratios["high effort"]; !ok || ratio.Mean <= 0 {
+			t.Errorf("%s: high effort ratio is %+v", format, ratio)
+		}
+		if report.Minutes.Max > preferences.Effort.MaxDuration.Minutes() {
+			t.Errorf("%s: workouts last up to %.1f minutes", format, report.Minutes.Max)
+		}
+	}
+}
+
+func TestSimulateCountsFallbacks(t *testing.T) {
+	// No ground movement is low effort, so every cooldown falls back.
+	movementBank = []Movement{
+		{Name: "march", Reps: 10, Duration: time.Second, Position: Standing, Effort: Low},
+		{Name: "squat", Reps: 10, Duration: time.Second, Position: Standing, Effort: High},
+		{Name: "plank", Reps: 1, Duration: 30 * time.Second, Position: Ground, Effort: High},
+	}
+	defer mustLoadMovementBank(t)
+	for _, format := range []Format{Sequential, Circuit} {
+		report, err := Simulate(WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Format: format}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Fallbacks != 5 {
+			t.Errorf("%s: expected fallbacks in every workout, got %+v", format, report)
+		}
+	}
+}
+
+func TestSimulateCountsFailures(t *testing.T) {
+	movementBank = []Movement{{Name: "squat", Reps: 2, Duration: time.Second, Position: Standing, Effort: High}}
+	defer mustLoadMovementBank(t)
+	report, err := Simulate(WorkoutOptions{Preferences: DefaultWorkoutPreferences()}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failures != 5 || report.Unsatisfiable != 5 {
+		t.Errorf("expected every workout to be unsatisfiable, got %+v", report)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	got := summarize([]float64{5, 1, 3, 2, 4})
+	want := Summary{Min: 1, Mean: 3, P10: 1, P50: 3, P90: 5, Max: 5}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
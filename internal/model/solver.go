@@ -0,0 +1,268 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// SolverAttempts is how many candidate workouts the solver builds
+	// before keeping the best.
+	SolverAttempts = 24
+	// MinFillRatio is the least of MaxDuration a workout must fill.
+	MinFillRatio = 0.8
+	// RatioSlack widens the structure ratios, movements are too coarse to
+	// hit a narrow range exactly.
+	RatioSlack = 0.1
+	// ViolationCost outweighs any difference in the objective, so a
+	// workout meeting more constraints always wins.
+	ViolationCost = 10
+	// ScoreWeight and FocusWeight weigh the movements' mean log score and
+	// focus coverage against filling the workout.
+	ScoreWeight = 0.05
+	FocusWeight = 0.2
+)
+
+type (
+	// Violation is a constraint a workout doesn't meet.
+	Violation struct {
+		Constraint string `json:"constraint"`
+		Want       string `json:"want"`
+		Got        string `json:"got"`
+	}
+	// ConstraintError is returned when no workout the solver built met
+	// every constraint, with the violations of the closest one and, if the
+	// options asked for them, its diagnostics.
+	ConstraintError struct {
+		Violations  []Violation
+		Diagnostics *Diagnostics
+	}
+	// pool is a segment's candidates, Fallback if they were allowed any
+	// effort.
+	pool struct {
+		Movements []Movement
+		Fallback  bool
+	}
+	// segment is a stretch of the workout in one effort phase and planned
+	// position, ending End into the workout.
+	segment struct {
+		Phase    WorkoutEffortPhase
+		Position Position
+		End      time.Duration
+	}
+	// solution is a candidate workout and how much time went to each
+	// phase, by movements of the phase's efforts, and position.
+	solution struct {
+		movements  []Movement
+		total      time.Duration
+		phaseTime  map[WorkoutEffortPhase]time.Duration
+		groundTime time.Duration
+		// score is the mean log of the movements' selection scores.
+		score       float64
+		violations  []Violation
+		cost        float64
+		diagnostics Diagnostics
+	}
+)
+
+func (e *ConstraintError) Error() string {
+	problems := []string{}
+	for _, v := range e.Violations {
+		problems = append(problems, fmt.Sprintf("%s is %s, want %s", v.Constraint, v.Got, v.Want))
+	}
+	return "no workout meets the constraints: " + strings.Join(problems, "; ")
+}
+
+// solve builds SolverAttempts workouts and returns the best one meeting
+// every constraint. Equipment and the user's lists and profile are hard
+// filters on the candidates. Workouts must fill MinFillRatio of MaxDuration
+// without passing it and keep the structure's ratios within RatioSlack. The
+// objective is filling the most time, then the candidates' scores and
+// covering the focus preferences or, without any, the most focus areas.
+func solve(rng *rand.Rand, options WorkoutOptions) ([]Movement, *Diagnostics, error) {
+	pools := map[string]pool{}
+	var best *solution
+	for attempt := 0; attempt < SolverAttempts; attempt++ {
+		candidate, err := buildSolution(rng, options, pools)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidate.evaluate(options)
+		if best == nil || candidate.cost < best.cost {
+			best = &candidate
+		}
+	}
+	best.diagnostics.Attempts, best.diagnostics.Cost = SolverAttempts, best.cost
+	if len(best.violations) > 0 {
+		err := &ConstraintError{Violations: best.violations}
+		if options.Debug {
+			err.Diagnostics = &best.diagnostics
+		}
+		return nil, &best.diagnostics, err
+	}
+	return best.movements, &best.diagnostics, nil
+}
+
+// segments splits a workout of the durations from getWorkoutDurations into
+// stretches of one effort phase and position, in order.
+func segments(durations []time.Duration, total time.Duration) []segment {
+	warmupEnd, highEnd, standingEnd := durations[0], durations[0]+durations[1], durations[3]
+	ends := []time.Duration{total}
+	for _, end := range []time.Duration{warmupEnd, highEnd, standingEnd} {
+		if end > 0 && end < total {
+			ends = append(ends, end)
+		}
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i] < ends[j] })
+	out := []segment{}
+	start := time.Duration(0)
+	for _, end := range ends {
+		if end == start {
+			continue
+		}
+		// Label the segment by the phase just after its start, the phase
+		// functions put a boundary in the earlier phase.
+		at := start + 1
+		out = append(out, segment{Phase: getEffortPhase(at, warmupEnd, durations[1]),
+			Position: getPositionPhase(at, standingEnd), End: end})
+		start = end
+	}
+	return out
+}
+
+// buildSolution fills each segment with movements chosen by their scores,
+// never passing the segment's end. The first movement is always chosen so
+// no workout is empty.
+func buildSolution(rng *rand.Rand, options WorkoutOptions, pools map[string]pool) (solution, error) {
+	preferences := options.Preferences
+	total := preferences.Effort.maxDuration()
+	durations := getWorkoutDurations(rng, preferences)
+	s := solution{phaseTime: map[WorkoutEffortPhase]time.Duration{},
+		diagnostics: Diagnostics{Durations: phaseDurations(durations)}}
+	for _, seg := range segments(durations, total) {
+		p, err := segmentPool(seg, options, pools)
+		if err != nil {
+			return solution{}, err
+		}
+		slot := newSlot(seg, options, p)
+		if p.Fallback {
+			s.diagnostics.Fallbacks = append(s.diagnostics.Fallbacks,
+				Fallback{Phase: slot.Phase, Position: slot.Position, Efforts: slot.Efforts})
+		}
+		for {
+			candidates := options.ruleOut(p.Movements, s.movements)
+			if len(s.movements) > 0 {
+				candidates = fitWithin(candidates, seg.End-s.total)
+			}
+			if len(candidates) == 0 {
+				break
+			}
+			movement := choose(rng, candidates, s.movements, options)
+			slot.Movement, slot.Candidates = movement.Name, len(candidates)
+			s.diagnostics.Slots = append(s.diagnostics.Slots, slot)
+			s.score += math.Log(options.score(movement, s.movements) + 1e-9)
+			s.movements = append(s.movements, movement)
+			duration := movement.EstimateDuration() + EstimatedRestPerMovement
+			s.total += duration
+			if contains(effortsForPhase(seg.Phase), movement.Effort) {
+				// Movements from a fallback pool don't count towards the
+				// phase they stand in for.
+				s.phaseTime[seg.Phase] += duration
+			}
+			if seg.Position == Ground {
+				// Count the planned position so a profile keeping the user
+				// standing doesn't break the ratios.
+				s.groundTime += duration
+			}
+		}
+	}
+	if len(s.movements) > 0 {
+		s.score /= float64(len(s.movements))
+	}
+	return s, nil
+}
+
+// segmentPool returns the scaled candidates for the segment, falling back to
+// any effort if none match its phase.
+func segmentPool(seg segment, options WorkoutOptions, pools map[string]pool) (pool, error) {
+	efforts := effortsForPhase(seg.Phase)
+	position := options.Profile.position(seg.Position)
+	key := fmt.Sprint(position, efforts)
+	if p, ok := pools[key]; ok {
+		return p, nil
+	}
+	p := pool{Movements: queryMovements(position, efforts, options)}
+	if len(p.Movements) == 0 {
+		p = pool{Movements: queryMovements(position, allEfforts, options), Fallback: true}
+		if len(p.Movements) == 0 {
+			return pool{}, &UnsatisfiableError{Position: position, Efforts: allEfforts}
+		}
+	}
+	preferences := options.Preferences
+	p.Movements = options.Timings.calibrate(preferences.Effort.scaleAll(filterByFocus(p.Movements, preferences.Focus)))
+	pools[key] = p
+	return p, nil
+}
+
+// evaluate checks the solution's constraints and computes its cost, lower
+// is better. Each violation outweighs any difference in the objective.
+func (s *solution) evaluate(options WorkoutOptions) {
+	structure := options.Preferences.Structure
+	maxDuration := options.Preferences.Effort.maxDuration()
+	s.violations = nil
+	if s.total > maxDuration || float64(s.total) < MinFillRatio*float64(maxDuration) {
+		s.violations = append(s.violations, Violation{Constraint: "duration",
+			Want: fmt.Sprintf("%s to %s", time.Duration(MinFillRatio*float64(maxDuration)).Round(time.Second), maxDuration),
+			Got:  s.total.String()})
+	}
+	ratios := []struct {
+		name     string
+		ratio    float64
+		min, max float64
+	}{
+		{"warmup ratio", s.ratio(s.phaseTime[WarmupPhase]), structure.MinWarmupRatio, structure.MaxWarmupRatio},
+		{"high effort ratio", s.ratio(s.phaseTime[HighEffortPhase]), structure.MinHighEffortRatio, structure.MaxHighEffortRatio},
+		{"cooldown ratio", s.ratio(s.phaseTime[CooldownPhase]), structure.MinCooldownRatio, structure.MaxCooldownRatio},
+		{"standing ratio", s.ratio(s.total - s.groundTime), structure.MinStandingRatio, structure.MaxStandingRatio},
+		{"ground ratio", s.ratio(s.groundTime), structure.MinGroundRatio, structure.MaxGroundRatio},
+	}
+	for _, r := range ratios {
+		if r.ratio < r.min-RatioSlack || r.ratio > r.max+RatioSlack {
+			s.violations = append(s.violations, Violation{Constraint: r.name,
+				Want: fmt.Sprintf("%.2f to %.2f", r.min, r.max), Got: fmt.Sprintf("%.2f", r.ratio)})
+		}
+	}
+	fill := math.Min(1, float64(s.total)/float64(maxDuration))
+	s.cost = ViolationCost*float64(len(s.violations)) + (1 - fill) - ScoreWeight*s.score +
+		FocusWeight*(1-s.focusCoverage(options.Preferences.Focus))
+}
+
+func (s *solution) ratio(d time.Duration) float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return float64(d) / float64(s.total)
+}
+
+// focusCoverage is the share of the focus areas targeted by a movement, the
+// preferred ones if there are any.
+func (s *solution) focusCoverage(preferred []Focus) float64 {
+	areas := preferred
+	if len(areas) == 0 {
+		areas = allFocus
+	}
+	covered := 0
+	for _, focus := range areas {
+		for _, movement := range s.movements {
+			if containsFocus(movement.Focus, focus) {
+				covered++
+				break
+			}
+		}
+	}
+	return float64(covered) / float64(len(areas))
+}
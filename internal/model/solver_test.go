@@ -0,0 +1,109 @@
+package model
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSegments(t *testing.T) {
+	// 2m warmup, 3m high effort, 3m cooldown, standing for the first 4m.
+	durations := []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	want := []segment{
+		{WarmupPhase, Standing, 2 * time.Minute},
+		{HighEffortPhase, Standing, 4 * time.Minute},
+		{HighEffortPhase, Ground, 5 * time.Minute},
+		{CooldownPhase, Ground, 8 * time.Minute},
+	}
+	got := segments(durations, 8*time.Minute)
+	if len(got) != len(want) {
+		t.Fatalf("got segments %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSolverKeepsRatios(t *testing.T) {
+	mustLoadMovementBank(t)
+	preferences := DefaultWorkoutPreferences()
+	preferences.Effort.MaxDuration = 20 * time.Minute
+	preferences.Structure.MinStandingRatio, preferences.Structure.MaxStandingRatio = 0.5, 0.5
+	preferences.Structure.MinGroundRatio, preferences.Structure.MaxGroundRatio = 0.5, 0.5
+	rng := newTestRand(t)
+	for i := 0; i < 20; i++ {
+		selection := mustSelectWith(t, rng, WorkoutOptions{Preferences: preferences})
+		total, standing := sequenceDuration(selection), time.Duration(0)
+		for _, movement := range selection {
+			if movement.Position == Standing {
+				standing += movement.EstimateDuration() + EstimatedRestPerMovement
+			}
+		}
+		if ratio := float64(standing) / float64(total); ratio < 0.5-RatioSlack || ratio > 0.5+RatioSlack {
+			t.Errorf("standing for %.2f of the workout, want 0.5", ratio)
+		}
+		if total > preferences.Effort.MaxDuration || float64(total) < MinFillRatio*float64(preferences.Effort.MaxDuration) {
+			t.Errorf("workout lasts %s of %s", total, preferences.Effort.MaxDuration)
+		}
+	}
+}
+
+func TestSolverExplainsUnmetConstraints(t *testing.T) {
+	// Every movement takes five minutes, so only one fits in eight.
+	movementBank = []Movement{}
+	for _, position := range allPositions {
+		for _, effort := range allEfforts {
+			movementBank = append(movementBank, Movement{Name: string(position) + " " + string(effort),
+				Reps: 100, Duration: time.Second, Position: position, Effort: effort})
+		}
+	}
+	defer mustLoadMovementBank(t)
+	_, err := movementSelection(newTestRand(t), WorkoutOptions{Preferences: DefaultWorkoutPreferences()})
+	var constraint *ConstraintError
+	if !errors.As(err, &constraint) {
+		t.Fatalf("expected a ConstraintError, got %v", err)
+	}
+	if constraint.Violations[0].Constraint != "duration" {
+		t.Errorf("expected the duration to be violated first, got %+v", constraint.Violations)
+	}
+}
+
+func TestSolverDoesNotCountFallbacksTowardsPhases(t *testing.T) {
+	// No ground movement is low effort, so the cooldown falls back to
+	// planks that shouldn't count as cooldown.
+	movementBank = []Movement{
+		{Name: "march", Reps: 10, Duration: time.Second, Position: Standing, Effort: Low},
+		{Name: "squat", Reps: 10, Duration: time.Second, Position: Standing, Effort: High},
+		{Name: "plank", Reps: 1, Duration: 30 * time.Second, Position: Ground, Effort: High},
+	}
+	defer mustLoadMovementBank(t)
+	preferences := DefaultWorkoutPreferences()
+	preferences.Effort.MaxDuration = 5 * time.Minute
+	s, err := buildSolution(newTestRand(t), WorkoutOptions{Preferences: preferences}, map[string]pool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cooldown := time.Duration(0)
+	for i, slot := range s.diagnostics.Slots {
+		movement := s.movements[i]
+		if slot.Phase == WorkoutEffortPhase(CooldownPhase).String() && movement.Effort == Low {
+			cooldown += movement.EstimateDuration() + EstimatedRestPerMovement
+		}
+	}
+	if s.phaseTime[CooldownPhase] != cooldown {
+		t.Errorf("cooldown counts %s, want %s of low effort movements", s.phaseTime[CooldownPhase], cooldown)
+	}
+}
+
+func mustSelectWith(t *testing.T, rng *rand.Rand, options WorkoutOptions) []Movement {
+	t.Helper()
+	options.Seed = rng.Int63()
+	workout, err := MakeWorkoutWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workout.Movements
+}
@@ -0,0 +1,66 @@
+package model
+
+import "math/rand"
+
+// Swap replaces the movement at index with another movement for the same
+// phase and position, chosen by the options' seed. Repeats of the movement
+// later in the same block are replaced too so rounds stay alike. Movements
+// already in the workout are never chosen and those in options.Swapped are
+// chosen less often. It returns the replacement.
+func (workout *Workout) Swap(index int, options WorkoutOptions) (Movement, error) {
+	if index < workout.Done || index >= len(workout.Movements) {
+		return Movement{}, ErrSwapIndex
+	}
+	old := workout.Movements[index]
+	slot := MovementSlot{Phase: effortPhaseOf(old.Effort), Position: old.Position}
+	if index < len(workout.Slots) {
+		slot = workout.Slots[index]
+	}
+	// Like the slot, fall back to any effort if none has the phase's.
+	efforts := effortsForPhase(slot.Phase)
+	pool := queryMovements(slot.Position, efforts, options)
+	if len(pool) == 0 {
+		efforts = allEfforts
+		pool = queryMovements(slot.Position, efforts, options)
+	}
+	inWorkout := map[string]bool{}
+	for _, movement := range workout.Movements {
+		inWorkout[movement.Name] = true
+	}
+	candidates := []Movement{}
+	for _, movement := range pool {
+		if !inWorkout[movement.Name] {
+			candidates = append(candidates, movement)
+		}
+	}
+	if len(candidates) == 0 {
+		return Movement{}, &UnsatisfiableError{Position: slot.Position, Efforts: efforts}
+	}
+	preferences := options.Preferences
+	candidates = options.Timings.calibrate(preferences.Effort.scaleAll(filterByFocus(candidates, preferences.Focus)))
+	rng := rand.New(rand.NewSource(options.Seed))
+	replacement := choose(rng, candidates, workout.Movements, options)
+	// The movements may be shared with the stored workout.
+	workout.Movements = append([]Movement{}, workout.Movements...)
+	block, ok := workout.blockAt(index)
+	if !ok || block.Format == Sequential {
+		workout.Movements[index] = replacement
+		return replacement, nil
+	}
+	replacement = fitToBlock(replacement, block)
+	for i := index; i < block.End; i++ {
+		if workout.Movements[i].Name == old.Name {
+			workout.Movements[i] = replacement
+		}
+	}
+	return replacement, nil
+}
+
+// effortPhaseOf is the phase a movement of the effort is chosen for, for
+// workouts stored before their slots were.
+func effortPhaseOf(effort Effort) WorkoutEffortPhase {
+	if contains(effortsForPhase(HighEffortPhase), effort) {
+		return HighEffortPhase
+	}
+	return WarmupPhase
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSwapKeepsPhases(t *testing.T) {
+	mustLoadMovementBank(t)
+	rng := newTestRand(t)
+	workout, err := MakeWorkoutWithOptions(WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: rng.Int63()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range workout.Movements {
+		old := workout.Movements[i]
+		replacement, err := workout.Swap(i, WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: rng.Int63()})
+		var unsatisfiable *UnsatisfiableError
+		if errors.As(err, &unsatisfiable) {
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if replacement.Name == old.Name || workout.Movements[i].Name != replacement.Name {
+			t.Errorf("movement %d: %s was not replaced", i, old.Name)
+		}
+		slot := workout.Slots[i]
+		efforts := effortsForPhase(slot.Phase)
+		if replacement.Position != slot.Position ||
+			(contains(efforts, old.Effort) && !contains(efforts, replacement.Effort)) {
+			t.Errorf("movement %d: %s %s %s swapped for %s %s %s", i, old.Name, old.Position,
+				old.Effort, replacement.Name, replacement.Position, replacement.Effort)
+		}
+	}
+}
+
+func TestSwapUsesTheSlotPhase(t *testing.T) {
+	mustLoadMovementBank(t)
+	bank, _ := Movements()
+	var low Movement
+	for _, movement := range bank {
+		if movement.Effort == Low && movement.Position == Standing {
+			low = movement
+			break
+		}
+	}
+	// A high effort slot filled from its fallback.
+	workout := Workout{Movements: []Movement{low},
+		Slots: []MovementSlot{{Phase: HighEffortPhase, Position: Standing}}}
+	stored := workout.Movements
+	replacement, err := workout.Swap(0, WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(effortsForPhase(HighEffortPhase), replacement.Effort) {
+		t.Errorf("expected a high effort replacement for the slot, got %s %s", replacement.Name, replacement.Effort)
+	}
+	if stored[0].Name != low.Name {
+		t.Error("expected the swap not to change the movements it was given")
+	}
+}
+
+func TestSwapReplacesRepeatsInBlock(t *testing.T) {
+	mustLoadMovementBank(t)
+	workout := mustMakeFormat(t, Circuit, 1)
+	var circuit Block
+	for _, block := range workout.Blocks {
+		if block.Format == Circuit {
+			circuit = block
+		}
+	}
+	old := workout.Movements[circuit.Start].Name
+	replacement, err := workout.Swap(circuit.Start, WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range workout.Movements[circuit.Start:circuit.End] {
+		if step.Name == old {
+			t.Errorf("%s is still in the circuit after swapping it for %s", old, replacement.Name)
+		}
+	}
+}
+
+func TestSwapRejectsDoneMovements(t *testing.T) {
+	workout := Workout{Movements: []Movement{{Name: "squat"}}, Done: 1}
+	if _, err := workout.Swap(0, WorkoutOptions{}); !errors.Is(err, ErrSwapIndex) {
+		t.Errorf("expected ErrSwapIndex, got %v", err)
+	}
+}
+
+func TestChooseAvoidsSwappedMovements(t *testing.T) {
+	options := []Movement{{Name: "squat"}, {Name: "lunge"}}
+	swapped := map[string]int{"squat": 3}
+	rng := newTestRand(t)
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[choose(rng, options, nil, WorkoutOptions{Swapped: swapped}).Name]++
+	}
+	// squat has a quarter of lunge's weight, so about 200 of 1000 picks.
+	if counts["squat"] < 120 || counts["squat"] > 280 {
+		t.Errorf("expected squat to be picked about 200 times, got %d", counts["squat"])
+	}
+}
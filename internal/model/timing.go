@@ -0,0 +1,117 @@
+package model
+
+import "time"
+
+const (
+	// TimingWeight is how far a new observation moves a timing's ratio.
+	TimingWeight = 0.2
+	// MinTimingSamples observations are needed before a timing is trusted.
+	MinTimingSamples = 3
+	// MinPaceRatio and MaxPaceRatio bound the active time observations
+	// kept, outside them the movement was likely paused or skipped.
+	MinPaceRatio = 0.25
+	MaxPaceRatio = 4
+	// MaxObservedRest is the longest gap between movements counted as
+	// rest, a longer one is a break.
+	MaxObservedRest = 2 * time.Minute
+)
+
+type (
+	// Timing is how long something takes compared to its estimate, as an
+	// exponentially weighted ratio of measured to estimated time.
+	Timing struct {
+		Ratio   float64 `json:"ratio"`
+		Samples int     `json:"samples"`
+	}
+	// UserTiming is how a user's pace and rests between movements compare
+	// to the estimates.
+	UserTiming struct {
+		// Pace compares the user's active time to the estimate, after
+		// the movement's own timing.
+		Pace Timing `json:"pace"`
+		// Rest compares the gaps between movements to
+		// EstimatedRestPerMovement.
+		Rest Timing `json:"rest"`
+	}
+	// Timings calibrate a user's movement estimates from their measured
+	// sessions.
+	Timings struct {
+		// Movements compare the user's active time in each movement to
+		// its estimate, after their pace.
+		Movements map[string]Timing `json:"movements"`
+		User      UserTiming        `json:"user"`
+	}
+)
+
+// Observe returns the timing with a measured ratio folded in.
+func (timing Timing) Observe(ratio float64) Timing {
+	if timing.Samples == 0 {
+		return Timing{Ratio: ratio, Samples: 1}
+	}
+	return Timing{Ratio: timing.Ratio + TimingWeight*(ratio-timing.Ratio), Samples: timing.Samples + 1}
+}
+
+// Factor is what to multiply an estimate by, 1 until the timing has
+// MinTimingSamples.
+func (timing Timing) Factor() float64 {
+	if timing.Samples < MinTimingSamples {
+		return 1
+	}
+	return timing.Ratio
+}
+
+// ObserveActive folds in how long the movement took from start to end. It
+// reports false if the time is too far from the estimate to be trusted.
+func (timings Timings) ObserveActive(movement Movement, active time.Duration) (Timings, bool) {
+	base := movement.baseDuration()
+	if base <= 0 {
+		return timings, false
+	}
+	ratio := float64(active) / float64(base)
+	if ratio < MinPaceRatio || ratio > MaxPaceRatio {
+		return timings, false
+	}
+	movements := make(map[string]Timing, len(timings.Movements)+1)
+	for name, timing := range timings.Movements {
+		movements[name] = timing
+	}
+	// Each model learns what the other doesn't explain.
+	movementTiming := movements[movement.Name]
+	movements[movement.Name] = movementTiming.Observe(ratio / timings.User.Pace.Factor())
+	timings.User.Pace = timings.User.Pace.Observe(ratio / movementTiming.Factor())
+	timings.Movements = movements
+	return timings, true
+}
+
+// ObserveRest folds in the gap between finishing a movement and starting
+// the next. It reports false if the gap was a break.
+func (timings Timings) ObserveRest(rest time.Duration) (Timings, bool) {
+	if rest < 0 || rest > MaxObservedRest {
+		return timings, false
+	}
+	timings.User.Rest = timings.User.Rest.Observe(float64(rest) / float64(EstimatedRestPerMovement))
+	return timings, true
+}
+
+// Estimate is how long the movement should take the user, including the
+// rest beyond EstimatedRestPerMovement before the next movement.
+func (timings Timings) Estimate(movement Movement) time.Duration {
+	active := float64(movement.baseDuration()) * timings.Movements[movement.Name].Factor() * timings.User.Pace.Factor()
+	rest := (timings.User.Rest.Factor() - 1) * float64(EstimatedRestPerMovement)
+	return max(time.Second, time.Duration(active+rest).Round(time.Second))
+}
+
+// calibrate sets the movements' Estimate if the timings change it, and
+// clears any Estimate they came with.
+func (timings Timings) calibrate(movements []Movement) []Movement {
+	uncalibrated := len(timings.Movements) == 0 && timings.User == (UserTiming{})
+	calibrated := make([]Movement, len(movements))
+	for i, movement := range movements {
+		movement.Estimate = 0
+		if estimate := timings.Estimate(movement); !uncalibrated && estimate != movement.baseDuration() {
+			movement.Estimate = estimate
+		}
+		calibrated[i] = movement
+	}
+	return calibrated
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingObserve(t *testing.T) {
+	timing := Timing{}
+	if timing.Factor() != 1 {
+		t.Errorf("expected an empty timing not to change estimates")
+	}
+	for i := 0; i < MinTimingSamples; i++ {
+		timing = timing.Observe(2)
+	}
+	if timing.Factor() != 2 {
+		t.Errorf("expected a factor of 2 after %d samples, got %.2f", MinTimingSamples, timing.Factor())
+	}
+	timing = timing.Observe(1)
+	if want := 2 - TimingWeight; timing.Ratio != want {
+		t.Errorf("expected the ratio to move %.1f towards 1, got %.2f", TimingWeight, timing.Ratio)
+	}
+}
+
+func TestTimingsCalibrateEstimates(t *testing.T) {
+	squat := Movement{Name: "squat", Reps: 10, Duration: time.Second}
+	base := squat.EstimateDuration()
+	others := []Movement{{Name: "lunge", Reps: 10, Duration: time.Second},
+		{Name: "bridge", Reps: 10, Duration: time.Second}, {Name: "plank", Reps: 10, Duration: time.Second}}
+	timings := Timings{}
+	var ok bool
+	for i := 0; i < 20; i++ {
+		if timings, ok = timings.ObserveActive(squat, 2*base); !ok {
+			t.Fatal("expected twice the estimate to be observed")
+		}
+		for _, other := range others {
+			timings, _ = timings.ObserveActive(other, base)
+		}
+	}
+	if _, ok := timings.ObserveActive(squat, 10*base); ok {
+		t.Errorf("expected ten times the estimate to be discarded")
+	}
+	estimate := timings.Estimate(squat)
+	if estimate < 17*base/10 || estimate > 23*base/10 {
+		t.Errorf("expected the estimate to approach %s, got %s", 2*base, estimate)
+	}
+	// A slow movement shouldn't make the user's other movements slower.
+	unmeasured := Movement{Name: "squat jump", Reps: 10, Duration: time.Second}
+	if got := timings.Estimate(unmeasured); got > 12*base/10 {
+		t.Errorf("expected an unmeasured movement to stay near %s, got %s", base, got)
+	}
+	for i := 0; i < MinTimingSamples; i++ {
+		timings, _ = timings.ObserveRest(3 * EstimatedRestPerMovement)
+	}
+	if _, ok := timings.ObserveRest(MaxObservedRest + time.Second); ok {
+		t.Errorf("expected a break not to count as rest")
+	}
+	calibrated := timings.calibrate([]Movement{unmeasured})[0]
+	if want := base + 2*EstimatedRestPerMovement; calibrated.EstimateDuration() < want-time.Second ||
+		calibrated.EstimateDuration() > want+base/5 {
+		t.Errorf("expected longer rests to take about %s, got %s", want, calibrated.EstimateDuration())
+	}
+	if stale := (Timings{}).calibrate([]Movement{{Name: "squat", Estimate: time.Hour}})[0]; stale.Estimate != 0 {
+		t.Errorf("expected an estimate without timings to be cleared, got %s", stale.Estimate)
+	}
+}
+
+func TestSolverUsesCalibratedEstimates(t *testing.T) {
+	mustLoadMovementBank(t)
+	options := WorkoutOptions{Preferences: DefaultWorkoutPreferences(), Seed: 3}
+	for i := 0; i < MinTimingSamples; i++ {
+		// The user takes half again as long on everything.
+		options.Timings.User.Pace = options.Timings.User.Pace.Observe(1.5)
+	}
+	workout, err := MakeWorkoutWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := time.Duration(0)
+	for _, movement := range workout.Movements {
+		if movement.Estimate == 0 {
+			t.Fatalf("%s wasn't calibrated", movement.Name)
+		}
+		total += movement.EstimateDuration() + EstimatedRestPerMovement
+	}
+	if total > options.Preferences.Effort.MaxDuration {
+		t.Errorf("calibrated workout lasts %s of %s", total, options.Preferences.Effort.MaxDuration)
+	}
+}
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+type (
+	// BankProblem is a single issue found in a movement bank.
+	BankProblem struct {
+		// Movement is the movement's name, or its index if it has none.
+		Movement string `json:"movement"`
+		Field    string `json:"field,omitempty"`
+		Problem  string `json:"problem"`
+	}
+)
+
+// nonNullFields are the bank fields that may not be null.
+var nonNullFields = []string{"name", "reps", "duration", "position", "modality",
+	"focus", "switchSides", "effort"}
+
+func (p BankProblem) String() string {
+	if p.Field == "" {
+		return fmt.Sprintf("%s: %s", p.Movement, p.Problem)
+	}
+	return fmt.Sprintf("%s: %s %s", p.Movement, p.Field, p.Problem)
+}
+
+// Iterations are the named images the web page shows for the movement.
+func (movement Movement) Iterations() []string {
+	iterations := []string{"active"}
+	if movement.IterationNames != nil {
+		iterations = append([]string{}, movement.IterationNames...)
+	}
+	return append(iterations, "rest")
+}
+
+// ImagePaths returns the images the web page expects for the movement,
+// relative to the webpage directory.
+func (movement Movement) ImagePaths() []string {
+	iterations := movement.Iterations()
+	paths := make([]string, len(iterations))
+	for i, iteration := range iterations {
+		paths[i] = path.Join("movement_images", movement.Name, iteration+".png")
+	}
+	return paths
+}
+
+// ValidateBank checks a movements.json document, returning every problem
+// found. Images are checked against the webpage directory in images unless
+// it is nil.
+func ValidateBank(data []byte, images fs.FS) []BankProblem {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []BankProblem{{Movement: "bank", Problem: err.Error()}}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var bank []Movement
+	if err := decoder.Decode(&bank); err != nil {
+		return []BankProblem{{Movement: "bank", Problem: err.Error()}}
+	}
+	problems := []BankProblem{}
+	seen := map[string]bool{}
+	for i, movement := range bank {
+		id := movement.Name
+		if id == "" {
+			id = fmt.Sprintf("#%d", i)
+		}
+		report := func(field, format string, args ...interface{}) {
+			problems = append(problems, BankProblem{Movement: id, Field: field,
+				Problem: fmt.Sprintf(format, args...)})
+		}
+		for _, field := range nonNullFields {
+			for key, value := range raw[i] {
+				if strings.EqualFold(key, field) && string(value) == "null" {
+					report(field, "is null")
+				}
+			}
+		}
+		if movement.Name != "" && seen[movement.Name] {
+			report("name", "is a duplicate")
+		}
+		seen[movement.Name] = true
+		links := []struct {
+			field string
+			link  func(Movement) string
+		}{
+			{"regression", func(m Movement) string { return m.Regression }},
+			{"progression", func(m Movement) string { return m.Progression }},
+		}
+		for _, l := range links {
+			if name := l.link(movement); name != "" {
+				if _, found := findMovement(bank, name); !found {
+					report(l.field, "%q is not in the bank", name)
+				} else if name != movement.Name && leadsTo(bank, movement, movement.Name, l.link) {
+					report(l.field, "%q leads back to the movement", name)
+				}
+			}
+		}
+		for _, problem := range ValidateMovement(movement) {
+			problem.Movement = id
+			problems = append(problems, problem)
+		}
+		if images == nil || movement.Name == "" {
+			continue
+		}
+		for _, imagePath := range movement.ImagePaths() {
+			if _, err := fs.Stat(images, imagePath); err != nil {
+				report("image", "%s is missing", imagePath)
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateMovement checks a single movement's fields, durations may be in
+// either seconds or nanoseconds so are only checked to be positive.
+func ValidateMovement(movement Movement) []BankProblem {
+	problems := []BankProblem{}
+	report := func(field, format string, args ...interface{}) {
+		problems = append(problems, BankProblem{Movement: movement.Name, Field: field,
+			Problem: fmt.Sprintf(format, args...)})
+	}
+	if movement.Name == "" {
+		report("name", "is required")
+	} else if strings.ContainsAny(movement.Name, "/\\.") {
+		report("name", "may not contain path separators or dots")
+	}
+	for _, iteration := range movement.IterationNames {
+		if iteration == "" || iteration == "rest" || strings.ContainsAny(iteration, "/\\.") {
+			report("iterationNames", "%q is not a valid iteration name", iteration)
+		}
+	}
+	if movement.Reps <= 0 {
+		report("reps", "must be positive")
+	}
+	if movement.Duration <= 0 {
+		report("duration", "must be positive")
+	}
+	if movement.IterationsPerRep < 0 {
+		report("iterationsPerRep", "may not be negative")
+	}
+	if !containsPosition(allPositions, movement.Position) {
+		report("position", "%q is not one of %s", movement.Position, allPositions)
+	}
+	if !containsModality(allModalities, movement.Modality) {
+		report("modality", "%q is not one of %s", movement.Modality, allModalities)
+	}
+	if !contains(allEfforts, movement.Effort) {
+		report("effort", "%q is not one of %s", movement.Effort, allEfforts)
+	}
+	if len(movement.Focus) == 0 {
+		report("focus", "is required")
+	}
+	for _, focus := range movement.Focus {
+		if !containsFocus(allFocus, focus) {
+			report("focus", "%q is not one of %s", focus, allFocus)
+		}
+	}
+	for _, contraindication := range movement.Contraindications {
+		if !containsContraindication(allContraindications, contraindication) || contraindication == GroundWork {
+			report("contraindications", "%q is not one of %s", contraindication,
+				[]Contraindication{LoadedKneeFlexion, WristWeightBearing})
+		}
+	}
+	if movement.Regression != "" && movement.Regression == movement.Name {
+		report("regression", "may not be the movement itself")
+	}
+	if movement.Progression != "" && movement.Progression == movement.Name {
+		report("progression", "may not be the movement itself")
+	}
+	for _, requirement := range movement.Requirement {
+		if !containsRequirement(allRequirements, requirement) {
+			report("requirement", "%q is not one of %s", requirement, allRequirements)
+		}
+	}
+	return problems
+}
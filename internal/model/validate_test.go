@@ -0,0 +1,60 @@
+package model
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ekotlikoff/gofit/internal/static"
+)
+
+func TestEmbeddedBankIsValid(t *testing.T) {
+	images, err := fs.Sub(static.WebpageStaticFS, "webpage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range ValidateBank(static.MovementsFS, images) {
+		t.Error(problem)
+	}
+}
+
+func TestValidateBankFindsDrift(t *testing.T) {
+	data := []byte(`[
+		{"Name": "squat", "reps": 2, "Duration": 5, "Position": "standing",
+			"Modality": "strength", "Focus": ["hip"], "SwitchSides": null, "Effort": "high"},
+		{"Name": "squat", "reps": 0, "Duration": 5, "Position": "sitting",
+			"Modality": "flexiblity", "Focus": ["elbow"], "Requirement": ["rope"], "Effort": "high"}
+	]`)
+	images := fstest.MapFS{
+		"movement_images/squat/active.png": {},
+	}
+	want := map[BankProblem]bool{
+		{Movement: "squat", Field: "switchSides", Problem: "is null"}:                                                 true,
+		{Movement: "squat", Field: "image", Problem: "movement_images/squat/rest.png is missing"}:                     true,
+		{Movement: "squat", Field: "name", Problem: "is a duplicate"}:                                                 true,
+		{Movement: "squat", Field: "reps", Problem: "must be positive"}:                                               true,
+		{Movement: "squat", Field: "position", Problem: `"sitting" is not one of [standing ground]`}:                  true,
+		{Movement: "squat", Field: "modality", Problem: `"flexiblity" is not one of [strength flexibility mobility]`}: true,
+		{Movement: "squat", Field: "focus", Problem: `"elbow" is not one of [hip back knee shoulder wrist ankle]`}:    true,
+		{Movement: "squat", Field: "requirement", Problem: `"rope" is not one of [mat chair band]`}:                   true,
+	}
+	got := map[BankProblem]bool{}
+	for _, problem := range ValidateBank(data, images) {
+		if !want[problem] {
+			t.Errorf("unexpected problem %s", problem)
+		}
+		got[problem] = true
+	}
+	for problem := range want {
+		if !got[problem] {
+			t.Errorf("missing problem %s", problem)
+		}
+	}
+}
+
+func TestValidateBankRejectsUnknownFields(t *testing.T) {
+	data := []byte(`[{"Name": "squat", "Requirements": "mat"}]`)
+	if problems := ValidateBank(data, nil); len(problems) != 1 || problems[0].Movement != "bank" {
+		t.Errorf("expected the unknown Requirements field to be reported, got %v", problems)
+	}
+}
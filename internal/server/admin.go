@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// GetAdminSession is GetSession for endpoints restricted to admins.
+func GetAdminSession(w http.ResponseWriter, r *http.Request) *UserSession {
+	session := GetSession(w, r)
+	if session == nil {
+		return nil
+	}
+	if !isAdmin(w, session.Username) {
+		return nil
+	}
+	return session
+}
+
+// isAdmin checks that the user is an admin, responding with an error if not.
+func isAdmin(w http.ResponseWriter, username string) bool {
+	role, err := store.UserRole(username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println("ERROR loading role for", username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	} else if role != RoleAdmin {
+		log.Println("Forbidden, not an admin", username)
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// writeBankError responds with the problems that stopped a bank from loading.
+func writeBankError(w http.ResponseWriter, err error) {
+	log.Println("ERROR loading movement bank", err)
+	var bankErr *model.BankError
+	if errors.As(err, &bankErr) && len(bankErr.Problems) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(bankErr.Problems)
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func makeReloadMovementsHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			if GetAdminSession(w, r) == nil {
+				return
+			}
+			if err := model.ReloadBank(); err != nil {
+				writeBankError(w, err)
+				return
+			}
+			response := struct {
+				Movements int `json:"movements"`
+			}{model.BankSize()}
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
@@ -0,0 +1,16 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReloadMovementsRequiresAdmin(t *testing.T) {
+	cookie := newTestSession(t)
+	reload := makeReloadMovementsHandler()
+	if code := fetchStatus(t, reload, cookie, "POST", "/admin/movements/reload", ""); code != http.StatusForbidden {
+		t.Errorf("expected a non-admin to be forbidden, got %d", code)
+	}
+	store.SetUserRole("alice", RoleAdmin)
+	serve(t, reload, cookie, "POST", "/admin/movements/reload", "")
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -10,49 +11,51 @@ import (
 	"golang.org/x/crypto/argon2"
 )
 
-// Hashed passwords are stored on disk.
+// Hashed passwords used to be stored on disk here, they are now imported
+// into the Store.
 const (
-	authPath string = ".gofit/auth"
+	legacyAuthPath string = ".gofit/auth"
 )
 
-func initAuth() {
-	_, err := os.Stat(authPath)
+// importLegacyAuth copies any credentials left in legacyAuthPath into the
+// store so that existing users can still log in.
+func importLegacyAuth() {
+	entries, err := os.ReadDir(legacyAuthPath)
 	if err != nil {
-		err := os.MkdirAll(authPath, 0700)
+		return
+	}
+	for _, entry := range entries {
+		key, err := os.ReadFile(filepath.Join(legacyAuthPath, entry.Name()))
 		if err != nil {
-			log.Fatal(err)
+			log.Println("ERROR reading legacy credentials", err)
+			continue
+		}
+		err = store.AddUser(entry.Name(), key)
+		if err != nil && !errors.Is(err, ErrExists) {
+			log.Println("ERROR importing legacy credentials", err)
 		}
 	}
 }
 
 func hashAndStore(creds Credentials) error {
-	if authExists(creds) {
-		return fmt.Errorf("Username %s is taken", creds.Username)
-	}
 	key := argon2.IDKey([]byte(creds.Password), []byte(creds.Username), 1, 46*1024, 1, 32)
-	if err := os.WriteFile(filepath.Join(authPath, creds.Username), key, 0600); err != nil {
-		return err
+	err := store.AddUser(creds.Username, key)
+	if errors.Is(err, ErrExists) {
+		return fmt.Errorf("Username %s is taken", creds.Username)
 	}
-	return nil
+	return err
 }
 
 func auth(creds Credentials) error {
-	if !authExists(creds) {
+	storedKey, err := store.UserKey(creds.Username)
+	if errors.Is(err, ErrNotFound) {
 		return fmt.Errorf("Username %s does not exist", creds.Username)
-	}
-	key := argon2.IDKey([]byte(creds.Password), []byte(creds.Username), 1, 46*1024, 1, 32)
-	storedKey, err := os.ReadFile(filepath.Join(authPath, creds.Username))
-	if err != nil {
+	} else if err != nil {
 		return err
 	}
+	key := argon2.IDKey([]byte(creds.Password), []byte(creds.Username), 1, 46*1024, 1, 32)
 	if bytes.Equal(key, storedKey) {
 		return nil
 	}
 	return fmt.Errorf("Login failed for %s", creds.Username)
 }
-
-// authExists checks if a username is already taken
-func authExists(creds Credentials) bool {
-	_, err := os.Stat(filepath.Join(authPath, creds.Username))
-	return err == nil
-}
@@ -0,0 +1,438 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	usersBucket        = []byte("users")
+	sessionsBucket     = []byte("sessions")
+	userSessionsBucket = []byte("userSessions")
+	preferencesBucket  = []byte("preferences")
+	// historyBucket holds a nested bucket per user keyed by sequence number.
+	historyBucket = []byte("history")
+	// swapsBucket holds a nested bucket per user keyed by sequence number.
+	swapsBucket         = []byte("swaps")
+	movementListsBucket = []byte("movementLists")
+	profilesBucket      = []byte("profiles")
+	progressBucket      = []byte("progress")
+	enrollmentsBucket   = []byte("enrollments")
+	timingsBucket       = []byte("userTimings")
+	rolesBucket         = []byte("roles")
+	movementsBucket     = []byte("movements")
+	imagesBucket        = []byte("images")
+)
+
+// boltStore is a Store backed by a single bbolt file.
+type boltStore struct {
+	db   *bolt.DB
+	done chan struct{}
+}
+
+// boltSession is a session as stored on disk.
+type boltSession struct {
+	Username   string `json:"username"`
+	LastAccess int64  `json:"lastAccess"`
+}
+
+// NewBoltStore opens or creates the bbolt file at path.
+func NewBoltStore(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{usersBucket, sessionsBucket,
+			userSessionsBucket, preferencesBucket, historyBucket, swapsBucket,
+			movementListsBucket, profilesBucket, progressBucket, enrollmentsBucket,
+			timingsBucket, rolesBucket, movementsBucket, imagesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &boltStore{db: db, done: make(chan struct{})}
+	go s.expireSessions()
+	return s, nil
+}
+
+// expireSessions periodically deletes sessions that outlived sessionTTL.
+func (s *boltStore) expireSessions() {
+	ticker := time.NewTicker(sessionGCFrequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.db.Update(func(tx *bolt.Tx) error {
+				c := tx.Bucket(sessionsBucket).Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					var session boltSession
+					if json.Unmarshal(v, &session) != nil ||
+						now.Unix()-session.LastAccess > int64(sessionTTL.Seconds()) {
+						c.Delete()
+					}
+				}
+				return nil
+			})
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *boltStore) AddUser(username string, key []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		if b.Get([]byte(username)) != nil {
+			return ErrExists
+		}
+		return b.Put([]byte(username), key)
+	})
+}
+
+func (s *boltStore) UserKey(username string) ([]byte, error) {
+	var key []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(usersBucket).Get([]byte(username))
+		if v == nil {
+			return ErrNotFound
+		}
+		key = append([]byte{}, v...)
+		return nil
+	})
+	return key, err
+}
+
+func (s *boltStore) AddSession(token, username string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sessionsBucket)
+		if b.Get([]byte(token)) != nil {
+			return ErrExists
+		}
+		return putJSON(b, token, boltSession{Username: username, LastAccess: time.Now().Unix()})
+	})
+}
+
+// SessionUser only writes the session's LastAccess once it is
+// sessionGCFrequency old, so most requests don't wait on a sync to disk.
+func (s *boltStore) SessionUser(token string) (string, error) {
+	var session boltSession
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(sessionsBucket), token, &session)
+	})
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if now.Sub(time.Unix(session.LastAccess, 0)) < sessionGCFrequency {
+		return session.Username, nil
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sessionsBucket)
+		if err := getJSON(b, token, &session); err != nil {
+			return err
+		}
+		session.LastAccess = now.Unix()
+		return putJSON(b, token, session)
+	})
+	return session.Username, err
+}
+
+func (s *boltStore) SessionCount() int {
+	count := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(sessionsBucket).Stats().KeyN
+		return nil
+	})
+	return count
+}
+
+func (s *boltStore) UserSession(username string) (UserSession, error) {
+	session := GetUser(username)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := getJSON(tx.Bucket(userSessionsBucket), username, &session)
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	})
+	return session, err
+}
+
+func (s *boltStore) PutUserSession(session UserSession) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(userSessionsBucket), session.Username, session)
+	})
+}
+
+func (s *boltStore) Preferences(username string) (model.WorkoutPreferences, error) {
+	var preferences model.WorkoutPreferences
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(preferencesBucket), username, &preferences)
+	})
+	return preferences, err
+}
+
+func (s *boltStore) PutPreferences(username string, preferences model.WorkoutPreferences) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(preferencesBucket), username, preferences)
+	})
+}
+
+func (s *boltStore) AddHistory(username string, record WorkoutRecord) error {
+	return s.appendUserJSON(historyBucket, username, record)
+}
+
+func (s *boltStore) History(username string, offset, limit int) ([]WorkoutRecord, error) {
+	records := []WorkoutRecord{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket).Bucket([]byte(username))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		skipped := 0
+		for k, v := c.Last(); k != nil && len(records) < limit; k, v = c.Prev() {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			var record WorkoutRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+func (s *boltStore) AddSwap(username string, swap Swap) error {
+	return s.appendUserJSON(swapsBucket, username, swap)
+}
+
+func (s *boltStore) SwapCounts(username string, before time.Time) (map[string]int, error) {
+	counts := map[string]int{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(swapsBucket).Bucket([]byte(username))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var swap Swap
+			if err := json.Unmarshal(v, &swap); err != nil {
+				return err
+			}
+			if swap.SwappedAt.Before(before) {
+				counts[swap.Movement]++
+			}
+			return nil
+		})
+	})
+	return counts, err
+}
+
+func (s *boltStore) MovementLists(username string) (MovementLists, error) {
+	var lists MovementLists
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(movementListsBucket), username, &lists)
+	})
+	return lists, err
+}
+
+func (s *boltStore) PutMovementLists(username string, lists MovementLists) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(movementListsBucket), username, lists)
+	})
+}
+
+func (s *boltStore) Profile(username string) (model.Profile, error) {
+	var profile model.Profile
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(profilesBucket), username, &profile)
+	})
+	return profile, err
+}
+
+func (s *boltStore) PutProfile(username string, profile model.Profile) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(profilesBucket), username, profile)
+	})
+}
+
+func (s *boltStore) Progress(username string) (model.Progress, error) {
+	var progress model.Progress
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(progressBucket), username, &progress)
+	})
+	return progress, err
+}
+
+func (s *boltStore) PutProgress(username string, progress model.Progress) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(progressBucket), username, progress)
+	})
+}
+
+func (s *boltStore) Enrollment(username string) (model.Enrollment, error) {
+	var enrollment model.Enrollment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(enrollmentsBucket), username, &enrollment)
+	})
+	return enrollment, err
+}
+
+func (s *boltStore) PutEnrollment(username string, enrollment model.Enrollment) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(enrollmentsBucket), username, enrollment)
+	})
+}
+
+func (s *boltStore) DeleteEnrollment(username string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(enrollmentsBucket).Delete([]byte(username))
+	})
+}
+
+func (s *boltStore) Timings(username string) (model.Timings, error) {
+	var timings model.Timings
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(timingsBucket), username, &timings)
+	})
+	return timings, err
+}
+
+func (s *boltStore) UpdateTimings(username string, update func(model.Timings) model.Timings) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var timings model.Timings
+		b := tx.Bucket(timingsBucket)
+		if err := getJSON(b, username, &timings); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return putJSON(b, username, update(timings))
+	})
+}
+
+func (s *boltStore) UserRole(username string) (string, error) {
+	var role string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(rolesBucket).Get([]byte(username))
+		if v == nil {
+			return ErrNotFound
+		}
+		role = string(v)
+		return nil
+	})
+	return role, err
+}
+
+func (s *boltStore) SetUserRole(username, role string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(rolesBucket).Put([]byte(username), []byte(role))
+	})
+}
+
+func (s *boltStore) Movements() ([]model.Movement, error) {
+	movements := []model.Movement{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(movementsBucket).ForEach(func(k, v []byte) error {
+			var movement model.Movement
+			if err := json.Unmarshal(v, &movement); err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+			return nil
+		})
+	})
+	return movements, err
+}
+
+func (s *boltStore) PutMovement(movement model.Movement) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(movementsBucket), movement.Name, movement)
+	})
+}
+
+func (s *boltStore) MovementImage(name, iteration string) ([]byte, error) {
+	var image []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(imagesBucket).Get([]byte(imageKey(name, iteration)))
+		if v == nil {
+			return ErrNotFound
+		}
+		image = append([]byte{}, v...)
+		return nil
+	})
+	return image, err
+}
+
+func (s *boltStore) PutMovementImage(name, iteration string, image []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(imagesBucket).Put([]byte(imageKey(name, iteration)), image)
+	})
+}
+
+func (s *boltStore) Close() error {
+	close(s.done)
+	return s.db.Close()
+}
+
+// appendUserJSON adds v to the user's nested bucket in bucket, keyed by
+// sequence number.
+func (s *boltStore) appendUserJSON(bucket []byte, username string, v interface{}) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(bucket).CreateBucketIfNotExists([]byte(username))
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return b.Put(sequenceKey(seq), data)
+	})
+}
+
+// sequenceKey encodes seq big endian so keys sort in insertion order.
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+func getJSON(b *bolt.Bucket, key string, v interface{}) error {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+func putJSON(b *bolt.Bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// makeFeedbackHandler takes the user's rating of their current workout and
+// adjusts the effort of their next ones. Movements flagged too hard also
+// step the user back along their progressions.
+func makeFeedbackHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			session, unlock := lockSession(w, r)
+			if session == nil {
+				return
+			}
+			defer unlock()
+			if session.WorkoutDay == "" || isRestDay(session.Workout) ||
+				(len(session.Completed) == 0 && !session.DoneForTheDay) {
+				// Only a workout that was at least started can be rated.
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("No workout to rate"))
+				return
+			} else if session.Feedback != nil {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte("The workout was already rated"))
+				return
+			}
+			var feedback model.Feedback
+			if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
+				log.Println("Bad request", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if err := feedback.Validate(session.Workout); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			preferences, err := loadPreferences(session.Username)
+			if err != nil {
+				log.Println("ERROR loading preferences", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			preferences.Effort = preferences.Effort.Adjust(feedback)
+			if err := store.PutPreferences(session.Username, preferences); err != nil {
+				log.Println("ERROR storing preferences", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if len(feedback.TooHard) > 0 {
+				stepBack(session.Username, feedback.TooHard)
+			}
+			session.Feedback = &feedback
+			if !putSession(w, session) {
+				return
+			}
+			if err := json.NewEncoder(w).Encode(preferences); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
+
+// stepBack returns the user from any of the movements they were promoted to.
+func stepBack(username string, names []string) {
+	progress, err := loadProgress(username)
+	if err != nil {
+		log.Println("ERROR loading progress for", username, err)
+		return
+	}
+	bank, err := model.Movements()
+	if err != nil {
+		log.Println("ERROR loading movements", err)
+		return
+	}
+	stepped := false
+	for _, name := range names {
+		stepped = progress.StepBack(bank, name) == nil || stepped
+	}
+	if !stepped {
+		return
+	}
+	if err := store.PutProgress(username, progress); err != nil {
+		log.Println("ERROR storing progress for", username, err)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func postFeedback(t *testing.T, cookie *http.Cookie, body string) int {
+	t.Helper()
+	return fetchStatus(t, makeFeedbackHandler(), cookie, "POST", "/workout/feedback", body)
+}
+
+func TestFeedbackAdjustsPreferences(t *testing.T) {
+	cookie := newTestSession(t)
+	if code := postFeedback(t, cookie, `{"rpe": 3}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 rating before any workout, got %d", code)
+	}
+	serve(t, makeFetchWorkoutHandler(), cookie, "POST", "/workout", `"4/1/2024"`)
+	if code := postFeedback(t, cookie, `{"rpe": 3}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 rating a workout not started, got %d", code)
+	}
+	serve(t, makeWorkoutUpdateHandler(), cookie, "POST", "/workoutUpdate", "")
+	if code := postFeedback(t, cookie, `{"rpe": 3, "tooEasy": ["handstand"]}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 flagging a movement not in the workout, got %d", code)
+	}
+
+	var preferences model.WorkoutPreferences
+	w := serve(t, makeFeedbackHandler(), cookie, "POST", "/workout/feedback", `{"rpe": 3}`)
+	if err := json.NewDecoder(w.Body).Decode(&preferences); err != nil {
+		t.Fatal(err)
+	}
+	defaults := model.DefaultWorkoutPreferences().Effort
+	if preferences.Effort.RepMultiplier <= defaults.RepMultiplier ||
+		preferences.Effort.MaxDuration != defaults.MaxDuration+model.DurationStep {
+		t.Errorf("expected an easy workout to raise the effort, got %+v", preferences.Effort)
+	}
+	if stored, _ := loadPreferences("alice"); stored.Effort != preferences.Effort {
+		t.Errorf("adjusted preferences were not stored, got %+v", stored.Effort)
+	}
+	if code := postFeedback(t, cookie, `{"rpe": 3}`); code != http.StatusConflict {
+		t.Errorf("expected 409 rating the workout twice, got %d", code)
+	}
+}
+
+func TestFeedbackTooHardStepsBack(t *testing.T) {
+	cookie := newTestSession(t)
+	store.PutProgress("alice", model.Progress{Mastered: []string{"sit to stand"}})
+	store.PutUserSession(UserSession{Username: "alice", WorkoutDay: "4/1/2024",
+		Workout:   model.Workout{Movements: []model.Movement{{Name: "staggered sit to stand"}}, Done: 1},
+		Completed: []Completion{{Movement: "staggered sit to stand"}}, DoneForTheDay: true})
+	serve(t, makeFeedbackHandler(), cookie, "POST", "/workout/feedback",
+		`{"rpe": 6, "tooHard": ["staggered sit to stand"]}`)
+	if progress, _ := loadProgress("alice"); len(progress.Mastered) != 0 {
+		t.Errorf("expected to step back to sit to stand, got %v", progress.Mastered)
+	}
+}
+
+func TestFeedbackRejectsRestDay(t *testing.T) {
+	cookie := newTestSession(t)
+	store.PutUserSession(UserSession{Username: "alice", WorkoutDay: "4/2/2024", DoneForTheDay: true,
+		Workout: model.Workout{Scheduled: &model.ScheduledDay{ProgramDay: model.ProgramDay{Rest: true}}}})
+	if code := postFeedback(t, cookie, `{"rpe": 5}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 rating a rest day, got %d", code)
+	}
+}
@@ -2,9 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ekotlikoff/gofit/internal/model"
 )
@@ -14,6 +19,25 @@ type UserSession struct {
 	DoneForTheDay bool          `json:"doneForTheDay"`
 	Workout       model.Workout `json:"workout"`
 	WorkoutDay    string        `json:"workoutDay"`
+	// StartedAt is when the workout was generated.
+	StartedAt time.Time `json:"startedAt"`
+	// FinishedAt is when the last movement was completed.
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Completed movements in the order they were done.
+	Completed []Completion `json:"completed"`
+	// Feedback the user rated the workout with, at most once.
+	Feedback *model.Feedback `json:"feedback,omitempty"`
+}
+
+// WorkoutUpdate is the optional body of a workout update.
+type WorkoutUpdate struct {
+	// EndBlock reports that the current block's time cap elapsed, its
+	// remaining steps are skipped.
+	EndBlock bool `json:"endBlock"`
+	// StartedAt and EndedAt are when the completed movement was started
+	// and finished by the client's clock, to calibrate estimates.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
 }
 
 // GetUser creates a user object for an authenticated user
@@ -30,15 +54,43 @@ func makeWorkoutUpdateHandler() http.Handler {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "POST":
-			session := GetSession(w, r)
+			session, unlock := lockSession(w, r)
 			if session == nil {
 				return
 			}
-			if session.Workout.Done >= len(session.Workout.Movements) {
-				session.DoneForTheDay = true
+			defer unlock()
+			var update WorkoutUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil && err != io.EOF {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Invalid workout update"))
+				return
+			}
+			if (update.StartedAt == nil) != (update.EndedAt == nil) ||
+				(update.StartedAt != nil && update.EndedAt.Before(*update.StartedAt)) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Invalid movement timing"))
 				return
 			}
-			session.Workout.Done++
+			now := time.Now()
+			if update.EndBlock {
+				if !session.Workout.EndBlock() {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte("The current block has no time cap"))
+					return
+				}
+				if session.Workout.Done >= len(session.Workout.Movements) {
+					session.FinishedAt = &now
+				}
+			} else if session.Workout.Done < len(session.Workout.Movements) {
+				movement := session.Workout.Movements[session.Workout.Done]
+				session.complete(now, update)
+				recordTiming(session, movement)
+			}
+			if session.Workout.Done >= len(session.Workout.Movements) && !session.DoneForTheDay {
+				session.DoneForTheDay = true
+				archive(session)
+			}
+			putSession(w, session)
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 
@@ -47,27 +99,125 @@ func makeWorkoutUpdateHandler() http.Handler {
 	return http.HandlerFunc(handler)
 }
 
+// writeWorkoutError responds to a failure to generate a workout.
+func writeWorkoutError(w http.ResponseWriter, err error) {
+	var unsatisfiable *model.UnsatisfiableError
+	var constraint *model.ConstraintError
+	if errors.As(err, &unsatisfiable) || errors.As(err, &constraint) {
+		log.Println("Unsatisfiable workout preferences", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	log.Println("ERROR generating workout", err)
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// loadWorkoutOptions gathers everything a user's workout on the day is
+// generated from, with a random seed. History from the day on is left out so
+// the day's workout can be regenerated from its seed after it was done.
+func loadWorkoutOptions(username string, day time.Time) (model.WorkoutOptions, error) {
+	preferences, err := loadPreferences(username)
+	if err != nil {
+		return model.WorkoutOptions{}, err
+	}
+	lists, err := loadMovementLists(username)
+	if err != nil {
+		return model.WorkoutOptions{}, err
+	}
+	profile, err := loadProfile(username)
+	if err != nil {
+		return model.WorkoutOptions{}, err
+	}
+	progress, err := loadProgress(username)
+	if err != nil {
+		return model.WorkoutOptions{}, err
+	}
+	timings, err := loadTimings(username)
+	if err != nil {
+		return model.WorkoutOptions{}, err
+	}
+	return model.WorkoutOptions{Preferences: preferences, Seed: rand.Int63(),
+		Swapped: swapCounts(username, day), Blocked: lists.Blocked, Favorites: lists.Favorites,
+		Profile: profile, Mastered: progress.Mastered, Recent: recentMovements(username, day),
+		Timings: timings}, nil
+}
+
 func makeFetchWorkoutHandler() http.Handler {
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case "POST":
-			session := GetSession(w, r)
+			session, unlock := lockSession(w, r)
 			if session == nil {
 				return
 			}
+			defer unlock()
 			var workoutDay string
 			err := json.NewDecoder(r.Body).Decode(&workoutDay)
 			if err != nil {
 				log.Println("ERROR parsing workout body")
 			}
-			workout := model.MakeWorkout()
+			day, ok := parseWorkoutDay(workoutDay, time.Now())
+			if !ok {
+				day = time.Now()
+			}
+			options, err := loadWorkoutOptions(session.Username, day)
+			if err != nil {
+				log.Println("ERROR loading workout options", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			options.Format = model.Format(r.URL.Query().Get("format"))
+			if options.Format != "" && !model.ValidFormat(options.Format) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Unknown workout format"))
+				return
+			}
+			if scheduled, ok := scheduledDay(session.Username, day); ok {
+				options.Scheduled = &scheduled
+			}
+			if r.URL.Query().Get("debug") == "1" {
+				// Diagnostics expose how workouts are generated.
+				if !isAdmin(w, session.Username) {
+					return
+				}
+				options.Debug = true
+			}
+			if v := r.URL.Query().Get("seed"); v != "" {
+				// Regenerate a shared or previously seen workout.
+				if options.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte("Invalid seed"))
+					return
+				}
+			}
+			workout, err := model.MakeWorkoutWithOptions(options)
+			if err != nil {
+				writeWorkoutError(w, err)
+				return
+			}
+			if session.WorkoutDay != "" && !session.DoneForTheDay {
+				// Finished workouts were archived when completed.
+				archive(session)
+			}
+			// Rest days are done before they start, and archived once so
+			// they don't break streaks. Diagnostics are only returned, not
+			// stored.
+			restedAlready := isRestDay(session.Workout) && session.WorkoutDay == workoutDay
+			stored := workout
+			stored.Diagnostics = nil
+			*session = UserSession{Username: session.Username, Workout: stored,
+				WorkoutDay: workoutDay, StartedAt: time.Now(), DoneForTheDay: len(workout.Movements) == 0}
+			if isRestDay(workout) && !restedAlready {
+				archive(session)
+			}
+			if !putSession(w, session) {
+				return
+			}
 			if err := json.NewEncoder(w).Encode(workout); err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			session.Workout = workout
-			session.DoneForTheDay = false
-			session.WorkoutDay = workoutDay
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 		}
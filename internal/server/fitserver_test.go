@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// newTestSession points the package at a fresh memory store and returns a
+// session cookie for a new user.
+func newTestSession(t *testing.T) *http.Cookie {
+	store = NewMemoryStore()
+	if err := store.AddSession("token", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	return &http.Cookie{Name: "session_token", Value: "token"}
+}
+
+func serve(t *testing.T, handler http.Handler, cookie *http.Cookie, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s %s: unexpected status %d %s", method, target, w.Code, w.Body.String())
+	}
+	return w
+}
+
+// fetchStatus makes a request and returns its status, for requests that may
+// fail.
+func fetchStatus(t *testing.T, handler http.Handler, cookie *http.Cookie, method, target, body string) int {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestFetchWorkoutWithSeed(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch := makeFetchWorkoutHandler()
+	first := serve(t, fetch, cookie, "POST", "/workout?seed=11", `"4/1/2024"`).Body.String()
+	second := serve(t, fetch, cookie, "POST", "/workout?seed=11", `"4/1/2024"`).Body.String()
+	if first != second {
+		t.Errorf("expected the same workout for the same seed")
+	}
+}
+
+func TestFetchWorkoutWithSeedAfterDoing(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch, update := makeFetchWorkoutHandler(), makeWorkoutUpdateHandler()
+	var first model.Workout
+	if err := json.NewDecoder(serve(t, fetch, cookie, "POST", "/workout?seed=11", `"4/1/2024"`).Body).Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	for range first.Movements {
+		serve(t, update, cookie, "POST", "/workoutUpdate", "")
+	}
+	if session, _ := store.UserSession("alice"); !session.DoneForTheDay {
+		t.Fatal("expected the workout to be done")
+	}
+	second := serve(t, fetch, cookie, "POST", "/workout?seed=11", `"4/1/2024"`).Body.String()
+	expected, _ := json.Marshal(first)
+	if strings.TrimSpace(second) != string(expected) {
+		t.Errorf("expected the same workout for the same seed and day after doing it")
+	}
+}
+
+func TestFetchWorkoutWithFormat(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch, update := makeFetchWorkoutHandler(), makeWorkoutUpdateHandler()
+	var workout model.Workout
+	body := serve(t, fetch, cookie, "POST", "/workout?format=amrap&seed=3", `"4/1/2024"`).Body
+	if err := json.NewDecoder(body).Decode(&workout); err != nil {
+		t.Fatal(err)
+	}
+	if workout.Format != model.AMRAP || len(workout.Blocks) == 0 {
+		t.Fatalf("expected an amrap workout, got %s with %d blocks", workout.Format, len(workout.Blocks))
+	}
+	var amrap model.Block
+	for _, block := range workout.Blocks {
+		if block.Format == model.AMRAP {
+			amrap = block
+		}
+	}
+	for i := 0; i <= amrap.Start; i++ {
+		serve(t, update, cookie, "POST", "/workoutUpdate", "")
+	}
+	serve(t, update, cookie, "POST", "/workoutUpdate", `{"endBlock": true}`)
+	session, err := store.UserSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Workout.Done != amrap.End || len(session.Completed) != amrap.Start+1 {
+		t.Errorf("expected the time cap to skip to step %d after %d completions, at %d after %d",
+			amrap.End, amrap.Start+1, session.Workout.Done, len(session.Completed))
+	}
+}
+
+func TestConcurrentWorkoutUpdates(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch, update := makeFetchWorkoutHandler(), makeWorkoutUpdateHandler()
+	serve(t, fetch, cookie, "POST", "/workout?seed=5", `"4/1/2024"`)
+	const updates = 8
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fetchStatus(t, update, cookie, "POST", "/workoutUpdate", "")
+		}()
+	}
+	wg.Wait()
+	session, err := store.UserSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Workout.Done != updates || len(session.Completed) != updates {
+		t.Errorf("expected %d completions, got %d with done at %d",
+			updates, len(session.Completed), session.Workout.Done)
+	}
+}
+
+func TestFetchWorkoutRejectsUnknownFormat(t *testing.T) {
+	cookie := newTestSession(t)
+	code := fetchStatus(t, makeFetchWorkoutHandler(), cookie, "POST", "/workout?format=yoga", `"4/1/2024"`)
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", code)
+	}
+}
+
+func TestFetchWorkoutDiagnosticsRequireAdmin(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch := makeFetchWorkoutHandler()
+	if code := fetchStatus(t, fetch, cookie, "POST", "/workout?debug=1", `"4/1/2024"`); code != http.StatusForbidden {
+		t.Errorf("expected a non-admin to be forbidden diagnostics, got %d", code)
+	}
+	store.SetUserRole("alice", RoleAdmin)
+	var workout model.Workout
+	body := serve(t, fetch, cookie, "POST", "/workout?debug=1&seed=2", `"4/1/2024"`).Body
+	if err := json.NewDecoder(body).Decode(&workout); err != nil {
+		t.Fatal(err)
+	}
+	if workout.Diagnostics == nil || len(workout.Diagnostics.Slots) == 0 {
+		t.Fatalf("expected diagnostics for an admin, got %+v", workout.Diagnostics)
+	}
+	session, err := store.UserSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Workout.Diagnostics != nil {
+		t.Errorf("expected diagnostics not to be stored with the session")
+	}
+}
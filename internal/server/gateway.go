@@ -6,9 +6,13 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/ekotlikoff/gofit/internal/model"
 	"github.com/ekotlikoff/gofit/internal/static"
 	"github.com/gofrs/uuid"
 	"github.com/prometheus/client_golang/prometheus"
@@ -19,10 +23,13 @@ const (
 	acceptableRequestPeriodMS   = 100
 	maxBurstOfRequests          = 10
 	maxTimeToWaitForRateLimiter = 2 * time.Second
+	movementsPollFrequency      = 5 * time.Second
 )
 
 var (
-	sessionCache *TTLMap
+	store Store
+	// sessionLocks hold a *sync.Mutex per username, see lockSession.
+	sessionLocks sync.Map
 
 	rateLimiter = make(chan time.Time, maxBurstOfRequests)
 
@@ -46,10 +53,10 @@ var (
 			Help:      "Total number of sessions in the cache.",
 		},
 		func() float64 {
-			if sessionCache == nil {
+			if store == nil {
 				return 0
 			}
-			return float64(sessionCache.Len())
+			return float64(store.SessionCount())
 		},
 	)
 
@@ -77,11 +84,6 @@ var (
 )
 
 func init() {
-	monthSecs := 30 * 24 * 60 * 60
-	hourSecs := 60 * 60
-	sessionCache = NewTTLMap(50, monthSecs, hourSecs)
-	initAuth()
-
 	prometheus.MustRegister(serverResponseMetric)
 	prometheus.MustRegister(serverResponseDurationMetric)
 	prometheus.MustRegister(serverRateLimiterMetric)
@@ -93,6 +95,17 @@ type (
 		Backend  *url.URL
 		BasePath string
 		Port     int
+		// Store persists users, sessions and workouts, defaults to an
+		// in-memory store.
+		Store Store
+		// MovementsPath is an external movement bank to load and watch
+		// instead of the embedded one.
+		MovementsPath string
+		// MovementImagesPath holds the external bank's movement_images,
+		// defaults to the directory containing MovementsPath.
+		MovementImagesPath string
+		// Admins are granted the admin role on startup.
+		Admins []string
 	}
 
 	// Credentials for authentication
@@ -104,8 +117,24 @@ type (
 
 // Serve static files and proxy to the different backends
 func (gw *Server) Serve() {
+	store = gw.Store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	importLegacyAuth()
+	for _, admin := range gw.Admins {
+		if err := store.SetUserRole(admin, RoleAdmin); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := refreshMovements(); err != nil {
+		log.Fatal(err)
+	}
 	cleanupChan := make(chan struct{})
 	setupRateLimiter(cleanupChan)
+	if gw.MovementsPath != "" {
+		gw.watchMovements(cleanupChan)
+	}
 	mux := http.NewServeMux()
 	bp := gw.BasePath
 	if len(bp) > 0 && (bp[len(bp)-1:] == "/" || bp[0:1] != "/") {
@@ -119,7 +148,22 @@ func (gw *Server) Serve() {
 	mux.Handle(bp+"/register", middleware(http.HandlerFunc(Register)))
 	mux.Handle(bp+"/session", middleware(http.HandlerFunc(Session)))
 	mux.Handle(bp+"/workout", middleware(makeFetchWorkoutHandler()))
+	mux.Handle(bp+"/workout/swap", middleware(makeSwapHandler()))
+	mux.Handle(bp+"/workout/feedback", middleware(makeFeedbackHandler()))
 	mux.Handle(bp+"/workoutUpdate", middleware(makeWorkoutUpdateHandler()))
+	mux.Handle(bp+"/preferences", middleware(makePreferencesHandler()))
+	mux.Handle(bp+"/profile", middleware(makeProfileHandler()))
+	mux.Handle(bp+"/progress", middleware(makeProgressHandler()))
+	mux.Handle(bp+"/programs", middleware(makeProgramsHandler()))
+	mux.Handle(bp+"/program", middleware(makeProgramHandler()))
+	mux.Handle(bp+"/progress/back", middleware(makeStepBackHandler()))
+	mux.Handle(bp+"/movements/blocked", middleware(makeMovementListHandler(blockedList)))
+	mux.Handle(bp+"/movements/favorites", middleware(makeMovementListHandler(favoritesList)))
+	mux.Handle(bp+"/history", middleware(makeHistoryHandler()))
+	mux.Handle(bp+"/stats", middleware(makeStatsHandler()))
+	mux.Handle(bp+"/admin/movements", middleware(makeMovementsHandler()))
+	mux.Handle(bp+"/admin/movements/images", middleware(makeMovementImagesHandler()))
+	mux.Handle(bp+"/admin/movements/reload", middleware(makeReloadMovementsHandler()))
 	// Prometheus metrics endpoint
 	mux.Handle(bp+"/metrics", middleware(
 		promhttp.Handler()))
@@ -145,13 +189,39 @@ func setupRateLimiter(cleanupChan chan struct{}) {
 	}()
 }
 
+// watchMovements loads the external movement bank and reloads it whenever it
+// changes.
+func (gw *Server) watchMovements(cleanupChan chan struct{}) {
+	imagesPath := gw.MovementImagesPath
+	if imagesPath == "" {
+		imagesPath = filepath.Dir(gw.MovementsPath)
+	}
+	if err := model.SetBankSource(gw.MovementsPath, imagesPath); err != nil {
+		log.Fatal(err)
+	}
+	go model.WatchBank(movementsPollFrequency, cleanupChan, func(err error) {
+		if err != nil {
+			log.Println("ERROR reloading movements, keeping the previous bank", err)
+			return
+		}
+		log.Println("Reloaded movements from", gw.MovementsPath)
+	})
+}
+
 func (gw *Server) handleWebRoot(w http.ResponseWriter, r *http.Request) {
 	bp := gw.BasePath
 	if len(bp) > 0 && len(r.URL.Path) > len(bp) && r.URL.Path[0:len(bp)] == bp {
-		r.URL.Path = "/webpage" + r.URL.Path[len(bp):]
-	} else {
-		r.URL.Path = "/webpage" + r.URL.Path // This is a hack to get the embedded path
+		r.URL.Path = r.URL.Path[len(bp):]
 	}
+	if strings.HasPrefix(r.URL.Path, "/movement_images/") {
+		// Images may come from the store or an external movement bank.
+		if serveStoredImage(w, r) {
+			return
+		}
+		http.FileServer(http.FS(model.BankImages())).ServeHTTP(w, r)
+		return
+	}
+	r.URL.Path = "/webpage" + r.URL.Path // This is a hack to get the embedded path
 	http.FileServer(http.FS(static.WebpageStaticFS)).ServeHTTP(w, r)
 }
 
@@ -230,11 +300,10 @@ func newSession(w http.ResponseWriter, r *http.Request, creds Credentials) {
 		return
 	}
 	sessionTokenStr := sessionToken.String()
-	user := GetUser(creds.Username)
-	log.Println("Adding to sessionCache,", creds.Username)
-	err = sessionCache.Put(sessionTokenStr, &user)
+	log.Println("Adding session,", creds.Username)
+	err = store.AddSession(sessionTokenStr, creds.Username)
 	if err != nil {
-		log.Println("Failed to store session token in sessionCache")
+		log.Println("Failed to store session token", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -247,30 +316,72 @@ func newSession(w http.ResponseWriter, r *http.Request, creds Credentials) {
 
 // GetSession credit to https://www.sohamkamani.com/blog/2018/03/25/golang-session-authentication/
 func GetSession(w http.ResponseWriter, r *http.Request) *UserSession {
+	username, ok := sessionUsername(w, r)
+	if !ok {
+		return nil
+	}
+	return loadSession(w, username)
+}
+
+// lockSession gets the session for a handler that changes it. The user's
+// session is locked until unlock is called, so concurrent updates are
+// applied one after another instead of overwriting each other.
+func lockSession(w http.ResponseWriter, r *http.Request) (session *UserSession, unlock func()) {
+	username, ok := sessionUsername(w, r)
+	if !ok {
+		return nil, nil
+	}
+	l, _ := sessionLocks.LoadOrStore(username, &sync.Mutex{})
+	lock := l.(*sync.Mutex)
+	lock.Lock()
+	if session = loadSession(w, username); session == nil {
+		lock.Unlock()
+		return nil, nil
+	}
+	return session, lock.Unlock
+}
+
+func sessionUsername(w http.ResponseWriter, r *http.Request) (string, bool) {
 	c, err := r.Cookie("session_token")
 	if err != nil {
 		if err == http.ErrNoCookie {
 			log.Println("session_token is not set")
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte("Missing session_token"))
-			return nil
+			return "", false
 		}
 		log.Println("ERROR", err)
 		w.WriteHeader(http.StatusBadRequest)
-		return nil
+		return "", false
 	}
 	sessionToken := c.Value
-	user, err := sessionCache.Get(sessionToken)
+	username, err := store.SessionUser(sessionToken)
 	if err != nil {
 		log.Println("ERROR token is invalid", sessionToken)
 		w.WriteHeader(http.StatusUnauthorized)
+		return "", false
+	}
+	return username, true
+}
+
+func loadSession(w http.ResponseWriter, username string) *UserSession {
+	user, err := store.UserSession(username)
+	if err != nil {
+		log.Println("ERROR loading session for", username, err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return nil
-	} else if user == nil {
-		log.Println("No user found for token ", sessionToken)
-		w.WriteHeader(http.StatusUnauthorized)
-		return nil
 	}
-	return user
+	return &user
+}
+
+// putSession stores changes a handler made to the user's session.
+func putSession(w http.ResponseWriter, session *UserSession) bool {
+	if err := store.PutUserSession(*session); err != nil {
+		log.Println("ERROR storing session for", session.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	return true
 }
 
 type statusWriter struct {
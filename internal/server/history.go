@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+const (
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+)
+
+type (
+	// Completion of a single movement in a workout.
+	Completion struct {
+		Movement string `json:"movement"`
+		// Index of the movement in the workout, which isn't the completion's
+		// position once an AMRAP block has been ended early.
+		Index       int       `json:"index"`
+		CompletedAt time.Time `json:"completedAt"`
+		// StartedAt and EndedAt are the client's measured times, if it
+		// reported them.
+		StartedAt *time.Time `json:"startedAt,omitempty"`
+		EndedAt   *time.Time `json:"endedAt,omitempty"`
+	}
+
+	// WorkoutRecord is a past workout, finished or abandoned.
+	WorkoutRecord struct {
+		WorkoutDay    string        `json:"workoutDay"`
+		DoneForTheDay bool          `json:"doneForTheDay"`
+		Workout       model.Workout `json:"workout"`
+		StartedAt     time.Time     `json:"startedAt"`
+		FinishedAt    *time.Time    `json:"finishedAt,omitempty"`
+		Completed     []Completion  `json:"completed"`
+	}
+)
+
+// complete marks the session's current movement as done.
+func (session *UserSession) complete(now time.Time, update WorkoutUpdate) {
+	movement := session.Workout.Movements[session.Workout.Done]
+	session.Completed = append(session.Completed, Completion{Movement: movement.Name,
+		Index: session.Workout.Done, CompletedAt: now, StartedAt: update.StartedAt, EndedAt: update.EndedAt})
+	session.Workout.Done++
+	if session.Workout.Done == len(session.Workout.Movements) {
+		session.FinishedAt = &now
+	}
+}
+
+func (session UserSession) record() WorkoutRecord {
+	return WorkoutRecord{
+		WorkoutDay:    session.WorkoutDay,
+		DoneForTheDay: session.DoneForTheDay,
+		Workout:       session.Workout,
+		StartedAt:     session.StartedAt,
+		FinishedAt:    session.FinishedAt,
+		Completed:     session.Completed,
+	}
+}
+
+// archive adds the session's workout to the user's history and counts its
+// completed movements towards their progressions.
+func archive(session *UserSession) {
+	if err := store.AddHistory(session.Username, session.record()); err != nil {
+		log.Println("ERROR storing history for", session.Username, err)
+	}
+	recordProgress(session)
+}
+
+// recentMovements returns the movements the user completed in the
+// model.RecentDays before the day, from their history.
+func recentMovements(username string, day time.Time) []model.RecentMovement {
+	records, err := store.History(username, 0, maxHistoryPageSize)
+	if err != nil {
+		log.Println("ERROR loading history for", username, err)
+		return nil
+	}
+	recent := []model.RecentMovement{}
+	for _, record := range records {
+		recordDay, ok := parseWorkoutDay(record.WorkoutDay, record.StartedAt)
+		if !ok || !recordDay.Before(day) {
+			continue
+		}
+		daysAgo := int(day.Sub(recordDay) / (24 * time.Hour))
+		if daysAgo >= model.RecentDays {
+			continue
+		}
+		focus := map[string][]model.Focus{}
+		for _, movement := range record.Workout.Movements {
+			focus[movement.Name] = movement.Focus
+		}
+		for _, completion := range record.Completed {
+			recent = append(recent, model.RecentMovement{Name: completion.Movement,
+				Focus: focus[completion.Movement], DaysAgo: daysAgo})
+		}
+	}
+	return recent
+}
+
+// pageParams parses the offset and limit query parameters.
+func pageParams(r *http.Request) (int, int, bool) {
+	offset, limit := 0, defaultHistoryPageSize
+	var err error
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, false
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, false
+		}
+	}
+	return offset, min(limit, maxHistoryPageSize), true
+}
+
+func makeHistoryHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			offset, limit, ok := pageParams(r)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Invalid offset or limit"))
+				return
+			}
+			records, err := store.History(session.Username, offset, limit)
+			if err != nil {
+				log.Println("ERROR loading history", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(records); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestHistoryRecordsCompletedWorkouts(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch, update, history := makeFetchWorkoutHandler(), makeWorkoutUpdateHandler(), makeHistoryHandler()
+	serve(t, fetch, cookie, "POST", "/workout", `"4/1/2024"`)
+	session, _ := store.UserSession("alice")
+	for range session.Workout.Movements {
+		serve(t, update, cookie, "POST", "/workoutUpdate", "")
+	}
+	// An abandoned workout is archived when it is replaced.
+	serve(t, fetch, cookie, "POST", "/workout", `"4/2/2024"`)
+	serve(t, update, cookie, "POST", "/workoutUpdate", "")
+	serve(t, fetch, cookie, "POST", "/workout", `"4/3/2024"`)
+
+	var records []WorkoutRecord
+	w := serve(t, history, cookie, "GET", "/history?limit=5", "")
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	abandoned, finished := records[0], records[1]
+	if abandoned.WorkoutDay != "4/2/2024" || abandoned.DoneForTheDay || len(abandoned.Completed) != 1 || abandoned.FinishedAt != nil {
+		t.Errorf("unexpected abandoned record %+v", abandoned)
+	}
+	if finished.WorkoutDay != "4/1/2024" || !finished.DoneForTheDay || finished.FinishedAt == nil ||
+		len(finished.Completed) != len(finished.Workout.Movements) {
+		t.Errorf("unexpected finished record %+v", finished)
+	}
+	if finished.Completed[0].Movement != finished.Workout.Movements[0].Name {
+		t.Errorf("expected %s to be completed first, got %s", finished.Workout.Movements[0].Name, finished.Completed[0].Movement)
+	}
+	w = serve(t, history, cookie, "GET", "/history?offset=1&limit=1", "")
+	records = nil
+	json.NewDecoder(w.Body).Decode(&records)
+	if len(records) != 1 || records[0].WorkoutDay != "4/1/2024" {
+		t.Errorf("unexpected second page %+v", records)
+	}
+}
+
+func TestRecentMovements(t *testing.T) {
+	newTestSession(t)
+	day := time.Date(2024, time.April, 12, 0, 0, 0, 0, time.UTC)
+	workout := model.Workout{Movements: []model.Movement{{Name: "squat", Focus: []model.Focus{model.Hip}}, {Name: "bridge"}}}
+	store.AddHistory("alice", WorkoutRecord{WorkoutDay: "2024-04-02", Workout: workout,
+		Completed: []Completion{{Movement: "squat"}}})
+	store.AddHistory("alice", WorkoutRecord{WorkoutDay: "2024-04-11", Workout: workout,
+		Completed: []Completion{{Movement: "squat"}, {Movement: "bridge"}}})
+	store.AddHistory("alice", WorkoutRecord{WorkoutDay: "2024-04-12", Workout: workout,
+		Completed: []Completion{{Movement: "bridge"}}})
+	recent := recentMovements("alice", day)
+	if len(recent) != 2 {
+		t.Fatalf("expected the two completions in the week before, got %+v", recent)
+	}
+	if recent[0].Name != "squat" || recent[0].DaysAgo != 1 || recent[0].Focus[0] != model.Hip || recent[1].DaysAgo != 1 {
+		t.Errorf("unexpected recent movements %+v", recent)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const (
+	blockedList   = "blocked"
+	favoritesList = "favorites"
+)
+
+// MovementLists are the movements a user never wants to see and those they
+// favor. A movement is in at most one of them.
+type MovementLists struct {
+	Blocked   []string `json:"blocked"`
+	Favorites []string `json:"favorites"`
+}
+
+// loadMovementLists returns the user's lists, empty if they have none.
+func loadMovementLists(username string) (MovementLists, error) {
+	lists, err := store.MovementLists(username)
+	if errors.Is(err, ErrNotFound) {
+		return MovementLists{Blocked: []string{}, Favorites: []string{}}, nil
+	}
+	return lists, err
+}
+
+// list returns the named list and the other one.
+func (lists *MovementLists) list(name string) (*[]string, *[]string) {
+	if name == blockedList {
+		return &lists.Blocked, &lists.Favorites
+	}
+	return &lists.Favorites, &lists.Blocked
+}
+
+func removeString(values []string, value string) []string {
+	out := []string{}
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// makeMovementListHandler manages one of the user's movement lists. GET
+// returns it, POST ?name= adds a movement, moving it out of the other list,
+// and DELETE ?name= removes one.
+func makeMovementListHandler(name string) http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "POST" && r.Method != "DELETE" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		session := GetSession(w, r)
+		if session == nil {
+			return
+		}
+		lists, err := loadMovementLists(session.Username)
+		if err != nil {
+			log.Println("ERROR loading movement lists", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		list, other := lists.list(name)
+		if r.Method != "GET" {
+			movement := r.URL.Query().Get("name")
+			if r.Method == "DELETE" {
+				*list = removeString(*list, movement)
+			} else if _, found, err := findMovement(movement); err != nil {
+				log.Println("ERROR finding movement", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			} else if !found {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("Unknown movement"))
+				return
+			} else if !containsString(*list, movement) {
+				*list = append(*list, movement)
+				*other = removeString(*other, movement)
+			}
+			if err := store.PutMovementLists(session.Username, lists); err != nil {
+				log.Println("ERROR storing movement lists", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := json.NewEncoder(w).Encode(*list); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
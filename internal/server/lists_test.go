@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func decodeList(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var list []string
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestMovementLists(t *testing.T) {
+	cookie := newTestSession(t)
+	blocked, favorites := makeMovementListHandler(blockedList), makeMovementListHandler(favoritesList)
+	if list := decodeList(t, serve(t, blocked, cookie, "GET", "/movements/blocked", "")); len(list) != 0 {
+		t.Errorf("expected an empty blocklist, got %v", list)
+	}
+	serve(t, favorites, cookie, "POST", "/movements/favorites?name=lunge", "")
+	if list := decodeList(t, serve(t, blocked, cookie, "POST", "/movements/blocked?name=lunge", "")); len(list) != 1 {
+		t.Errorf("expected lunge to be blocked, got %v", list)
+	}
+	if list := decodeList(t, serve(t, favorites, cookie, "GET", "/movements/favorites", "")); len(list) != 0 {
+		t.Errorf("expected blocking lunge to remove it from favorites, got %v", list)
+	}
+	if list := decodeList(t, serve(t, blocked, cookie, "DELETE", "/movements/blocked?name=lunge", "")); len(list) != 0 {
+		t.Errorf("expected lunge to be unblocked, got %v", list)
+	}
+
+	if code := fetchStatus(t, blocked, cookie, "POST", "/movements/blocked?name=handstand", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown movement, got %d", code)
+	}
+}
+
+func TestFetchWorkoutExcludesBlocked(t *testing.T) {
+	cookie := newTestSession(t)
+	for _, name := range []string{"lunge", "side lunge", "squat"} {
+		serve(t, makeMovementListHandler(blockedList), cookie, "POST", "/movements/blocked?name="+url.QueryEscape(name), "")
+	}
+	for seed := 0; seed < 10; seed++ {
+		var workout model.Workout
+		body := serve(t, makeFetchWorkoutHandler(), cookie, "POST", fmt.Sprintf("/workout?seed=%d", seed), `"4/1/2024"`).Body
+		if err := json.NewDecoder(body).Decode(&workout); err != nil {
+			t.Fatal(err)
+		}
+		for _, movement := range workout.Movements {
+			if movement.Name == "lunge" || movement.Name == "side lunge" || movement.Name == "squat" {
+				t.Fatalf("seed %d: blocked movement %s was selected", seed, movement.Name)
+			}
+		}
+	}
+}
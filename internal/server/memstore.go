@@ -0,0 +1,308 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// memoryStore is a Store that lives only as long as the process.
+type memoryStore struct {
+	sessions     *TTLMap
+	l            sync.Mutex
+	users        map[string][]byte
+	userSessions map[string]UserSession
+	preferences  map[string]model.WorkoutPreferences
+	history      map[string][]WorkoutRecord
+	swaps        map[string][]Swap
+	lists        map[string]MovementLists
+	profiles     map[string]model.Profile
+	progress     map[string]model.Progress
+	enrollments  map[string]model.Enrollment
+	timings      map[string]model.Timings
+	roles        map[string]string
+	movements    []model.Movement
+	images       map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory Store.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		sessions: NewTTLMap(50, int(sessionTTL.Seconds()),
+			int(sessionGCFrequency.Seconds())),
+		users:        map[string][]byte{},
+		userSessions: map[string]UserSession{},
+		preferences:  map[string]model.WorkoutPreferences{},
+		history:      map[string][]WorkoutRecord{},
+		swaps:        map[string][]Swap{},
+		lists:        map[string]MovementLists{},
+		profiles:     map[string]model.Profile{},
+		progress:     map[string]model.Progress{},
+		enrollments:  map[string]model.Enrollment{},
+		timings:      map[string]model.Timings{},
+		roles:        map[string]string{},
+		images:       map[string][]byte{},
+	}
+}
+
+func (s *memoryStore) AddUser(username string, key []byte) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if _, ok := s.users[username]; ok {
+		return ErrExists
+	}
+	s.users[username] = key
+	return nil
+}
+
+func (s *memoryStore) UserKey(username string) ([]byte, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	key, ok := s.users[username]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *memoryStore) AddSession(token, username string) error {
+	return s.sessions.Put(token, username)
+}
+
+func (s *memoryStore) SessionUser(token string) (string, error) {
+	username, err := s.sessions.Get(token)
+	if err != nil {
+		return "", ErrNotFound
+	}
+	return username, nil
+}
+
+func (s *memoryStore) SessionCount() int {
+	return s.sessions.Len()
+}
+
+func (s *memoryStore) UserSession(username string) (UserSession, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	session, ok := s.userSessions[username]
+	if !ok {
+		return GetUser(username), nil
+	}
+	return session, nil
+}
+
+func (s *memoryStore) PutUserSession(session UserSession) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.userSessions[session.Username] = session
+	return nil
+}
+
+func (s *memoryStore) Preferences(username string) (model.WorkoutPreferences, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	preferences, ok := s.preferences[username]
+	if !ok {
+		return model.WorkoutPreferences{}, ErrNotFound
+	}
+	return preferences, nil
+}
+
+func (s *memoryStore) PutPreferences(username string, preferences model.WorkoutPreferences) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.preferences[username] = preferences
+	return nil
+}
+
+func (s *memoryStore) AddHistory(username string, record WorkoutRecord) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.history[username] = append(s.history[username], record)
+	return nil
+}
+
+func (s *memoryStore) History(username string, offset, limit int) ([]WorkoutRecord, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	records := s.history[username]
+	out := []WorkoutRecord{}
+	for i := len(records) - 1 - offset; i >= 0 && len(out) < limit; i-- {
+		out = append(out, records[i])
+	}
+	return out, nil
+}
+
+func (s *memoryStore) AddSwap(username string, swap Swap) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.swaps[username] = append(s.swaps[username], swap)
+	return nil
+}
+
+func (s *memoryStore) SwapCounts(username string, before time.Time) (map[string]int, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	counts := map[string]int{}
+	for _, swap := range s.swaps[username] {
+		if swap.SwappedAt.Before(before) {
+			counts[swap.Movement]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *memoryStore) MovementLists(username string) (MovementLists, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	lists, ok := s.lists[username]
+	if !ok {
+		return MovementLists{}, ErrNotFound
+	}
+	return lists, nil
+}
+
+func (s *memoryStore) PutMovementLists(username string, lists MovementLists) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.lists[username] = lists
+	return nil
+}
+
+func (s *memoryStore) Profile(username string) (model.Profile, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	profile, ok := s.profiles[username]
+	if !ok {
+		return model.Profile{}, ErrNotFound
+	}
+	return profile, nil
+}
+
+func (s *memoryStore) PutProfile(username string, profile model.Profile) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.profiles[username] = profile
+	return nil
+}
+
+func (s *memoryStore) Progress(username string) (model.Progress, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	progress, ok := s.progress[username]
+	if !ok {
+		return model.Progress{}, ErrNotFound
+	}
+	return progress, nil
+}
+
+func (s *memoryStore) PutProgress(username string, progress model.Progress) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.progress[username] = progress
+	return nil
+}
+
+func (s *memoryStore) Enrollment(username string) (model.Enrollment, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	enrollment, ok := s.enrollments[username]
+	if !ok {
+		return model.Enrollment{}, ErrNotFound
+	}
+	return enrollment, nil
+}
+
+func (s *memoryStore) PutEnrollment(username string, enrollment model.Enrollment) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.enrollments[username] = enrollment
+	return nil
+}
+
+func (s *memoryStore) DeleteEnrollment(username string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	delete(s.enrollments, username)
+	return nil
+}
+
+func (s *memoryStore) Timings(username string) (model.Timings, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	timings, ok := s.timings[username]
+	if !ok {
+		return model.Timings{}, ErrNotFound
+	}
+	movements := make(map[string]model.Timing, len(timings.Movements))
+	for name, timing := range timings.Movements {
+		movements[name] = timing
+	}
+	timings.Movements = movements
+	return timings, nil
+}
+
+func (s *memoryStore) UpdateTimings(username string, update func(model.Timings) model.Timings) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.timings[username] = update(s.timings[username])
+	return nil
+}
+
+func (s *memoryStore) UserRole(username string) (string, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	role, ok := s.roles[username]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return role, nil
+}
+
+func (s *memoryStore) SetUserRole(username, role string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.roles[username] = role
+	return nil
+}
+
+func (s *memoryStore) Movements() ([]model.Movement, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return append([]model.Movement{}, s.movements...), nil
+}
+
+func (s *memoryStore) PutMovement(movement model.Movement) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	for i := range s.movements {
+		if s.movements[i].Name == movement.Name {
+			s.movements[i] = movement
+			return nil
+		}
+	}
+	s.movements = append(s.movements, movement)
+	return nil
+}
+
+func (s *memoryStore) MovementImage(name, iteration string) ([]byte, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	image, ok := s.images[imageKey(name, iteration)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return image, nil
+}
+
+func (s *memoryStore) PutMovementImage(name, iteration string, image []byte) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.images[imageKey(name, iteration)] = image
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
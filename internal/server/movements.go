@@ -0,0 +1,292 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// maxImageBytes bounds uploaded movement images.
+const maxImageBytes = 5 << 20
+
+type (
+	// MovementListing is a movement as seen by admins.
+	MovementListing struct {
+		model.Movement
+		// Custom movements were created or changed through the admin API.
+		Custom bool `json:"custom"`
+		// MissingImages keep a custom movement out of workouts until they
+		// are uploaded.
+		MissingImages []string `json:"missingImages,omitempty"`
+	}
+)
+
+// refreshMovements applies the stored movements to the movement bank. New
+// movements are left out until all of their images have been uploaded.
+func refreshMovements() error {
+	stored, err := store.Movements()
+	if err != nil {
+		return err
+	}
+	active := []model.Movement{}
+	for _, movement := range stored {
+		if movement.Retired || len(missingImages(movement)) == 0 {
+			active = append(active, movement)
+		}
+	}
+	return model.SetCustomMovements(active)
+}
+
+// missingImages returns the movement's iterations with no image in either
+// the store or the bank.
+func missingImages(movement model.Movement) []string {
+	missing := []string{}
+	for _, iteration := range movement.Iterations() {
+		if _, err := store.MovementImage(movement.Name, iteration); err == nil {
+			continue
+		}
+		imagePath := path.Join("movement_images", movement.Name, iteration+".png")
+		if _, err := fs.Stat(model.BankImages(), imagePath); err != nil {
+			missing = append(missing, iteration)
+		}
+	}
+	return missing
+}
+
+// listMovements returns the bank's movements and any stored movements still
+// waiting on images.
+func listMovements() ([]MovementListing, error) {
+	bank, err := model.Movements()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := store.Movements()
+	if err != nil {
+		return nil, err
+	}
+	custom := map[string]model.Movement{}
+	for _, movement := range stored {
+		custom[movement.Name] = movement
+	}
+	listings := []MovementListing{}
+	for _, movement := range bank {
+		_, isCustom := custom[movement.Name]
+		delete(custom, movement.Name)
+		listings = append(listings, MovementListing{Movement: movement, Custom: isCustom})
+	}
+	for _, movement := range stored {
+		if _, pending := custom[movement.Name]; pending {
+			listings = append(listings, MovementListing{Movement: movement, Custom: true,
+				MissingImages: missingImages(movement)})
+		}
+	}
+	return listings, nil
+}
+
+// findMovement looks a movement up in the store, then the bank.
+func findMovement(name string) (model.Movement, bool, error) {
+	stored, err := store.Movements()
+	if err != nil {
+		return model.Movement{}, false, err
+	}
+	for _, movement := range stored {
+		if movement.Name == name {
+			return movement, true, nil
+		}
+	}
+	bank, err := model.Movements()
+	if err != nil {
+		return model.Movement{}, false, err
+	}
+	for _, movement := range bank {
+		if movement.Name == name {
+			return movement, true, nil
+		}
+	}
+	return model.Movement{}, false, nil
+}
+
+// putMovement stores the movement and applies it to the bank.
+func putMovement(w http.ResponseWriter, movement model.Movement) {
+	if err := store.PutMovement(movement); err != nil {
+		log.Println("ERROR storing movement", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := refreshMovements(); err != nil {
+		writeBankError(w, err)
+		return
+	}
+	listing := MovementListing{Movement: movement, Custom: true, MissingImages: missingImages(movement)}
+	if err := json.NewEncoder(w).Encode(listing); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// decodeMovement reads and validates a movement from the request body. Its
+// duration is in nanoseconds like the rest of the API, unlike movements.json
+// which is in seconds, so anything under a second is rejected as a likely
+// mistake. Estimates are calibrated per user, so any sent is dropped.
+func decodeMovement(w http.ResponseWriter, r *http.Request) (model.Movement, bool) {
+	var movement model.Movement
+	if err := json.NewDecoder(r.Body).Decode(&movement); err != nil {
+		log.Println("Bad request", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return movement, false
+	}
+	movement.Estimate = 0
+	problems := model.ValidateMovement(movement)
+	if movement.Duration > 0 && movement.Duration < time.Second {
+		problems = append(problems, model.BankProblem{Movement: movement.Name, Field: "duration",
+			Problem: "must be at least a second, in nanoseconds"})
+	}
+	if len(problems) > 0 {
+		log.Println("Invalid movement", problems)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(problems)
+		return movement, false
+	}
+	return movement, true
+}
+
+func makeMovementsHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if GetAdminSession(w, r) == nil {
+			return
+		}
+		switch r.Method {
+		case "GET":
+			listings, err := listMovements()
+			if err != nil {
+				writeBankError(w, err)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(listings); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "POST", "PUT":
+			movement, ok := decodeMovement(w, r)
+			if !ok {
+				return
+			}
+			_, exists, err := findMovement(movement.Name)
+			if err != nil {
+				writeBankError(w, err)
+				return
+			} else if exists && r.Method == "POST" {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte("Movement already exists"))
+				return
+			} else if !exists && r.Method == "PUT" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			putMovement(w, movement)
+		case "DELETE":
+			// Movements are retired rather than deleted so history still
+			// refers to them.
+			movement, exists, err := findMovement(r.URL.Query().Get("name"))
+			if err != nil {
+				writeBankError(w, err)
+				return
+			} else if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			movement.Retired = true
+			putMovement(w, movement)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
+
+func makeMovementImagesHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			if GetAdminSession(w, r) == nil {
+				return
+			}
+			name, iteration := r.URL.Query().Get("name"), r.URL.Query().Get("iteration")
+			movement, exists, err := findMovement(name)
+			if err != nil {
+				writeBankError(w, err)
+				return
+			} else if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if !containsString(movement.Iterations(), iteration) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(fmt.Sprintf("Iteration must be one of %s", movement.Iterations())))
+				return
+			}
+			image, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImageBytes))
+			if err != nil {
+				log.Println("Bad request", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if _, err := png.DecodeConfig(bytes.NewReader(image)); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Image must be a png"))
+				return
+			}
+			if err := store.PutMovementImage(name, iteration, image); err != nil {
+				log.Println("ERROR storing image", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := refreshMovements(); err != nil {
+				writeBankError(w, err)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
+
+// serveStoredImage serves /movement_images/<name>/<iteration>.png from the
+// store, returning false if the store doesn't have it.
+func serveStoredImage(w http.ResponseWriter, r *http.Request) bool {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/movement_images/"), "/")
+	if len(parts) != 2 || !strings.HasSuffix(parts[1], ".png") {
+		return false
+	}
+	image, err := store.MovementImage(parts[0], strings.TrimSuffix(parts[1], ".png"))
+	if errors.Is(err, ErrNotFound) {
+		return false
+	} else if err != nil {
+		log.Println("ERROR loading image", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(image)
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
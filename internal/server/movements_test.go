@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func newAdminSession(t *testing.T) *http.Cookie {
+	cookie := newTestSession(t)
+	store.SetUserRole("alice", RoleAdmin)
+	t.Cleanup(func() { model.SetCustomMovements(nil) })
+	return cookie
+}
+
+func bankHas(t *testing.T, name string) (model.Movement, bool) {
+	movements, err := model.Movements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, movement := range movements {
+		if movement.Name == name {
+			return movement, true
+		}
+	}
+	return model.Movement{}, false
+}
+
+func TestMovementLifecycle(t *testing.T) {
+	cookie := newAdminSession(t)
+	movements, images := makeMovementsHandler(), makeMovementImagesHandler()
+	plank := `{"name": "plank", "reps": 3, "duration": 20000000000, "position": "ground",
+		"modality": "strength", "focus": ["back", "shoulder"], "effort": "high", "estimate": 3600000000000}`
+	var listing MovementListing
+	json.NewDecoder(serve(t, movements, cookie, "POST", "/admin/movements", plank).Body).Decode(&listing)
+	if listing.Estimate != 0 {
+		t.Errorf("expected the estimate sent to be dropped, got %s", listing.Estimate)
+	}
+	if len(listing.MissingImages) != 2 {
+		t.Errorf("expected active and rest images to be missing, got %v", listing.MissingImages)
+	}
+	if _, ok := bankHas(t, "plank"); ok {
+		t.Error("expected plank to wait for its images before joining the bank")
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	for _, iteration := range []string{"active", "rest"} {
+		serve(t, images, cookie, "PUT", "/admin/movements/images?name=plank&iteration="+iteration, buf.String())
+	}
+	if _, ok := bankHas(t, "plank"); !ok {
+		t.Error("expected plank to join the bank once its images are uploaded")
+	}
+	w := httptest.NewRecorder()
+	(&Server{}).handleWebRoot(w, httptest.NewRequest("GET", "/movement_images/plank/active.png", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("expected the stored image to be served, got %d", w.Code)
+	}
+
+	serve(t, movements, cookie, "PUT", "/admin/movements", `{"name": "squat", "reps": 20, "duration": 1000000000,
+		"position": "standing", "modality": "strength", "focus": ["knee"], "effort": "high"}`)
+	if squat, _ := bankHas(t, "squat"); squat.Reps != 20 {
+		t.Errorf("expected squat to be updated, got %d reps", squat.Reps)
+	}
+	serve(t, movements, cookie, "DELETE", "/admin/movements?name=squat", "")
+	if squat, _ := bankHas(t, "squat"); !squat.Retired {
+		t.Error("expected squat to be retired")
+	}
+}
+
+func TestMovementValidation(t *testing.T) {
+	cookie := newAdminSession(t)
+	movements := makeMovementsHandler()
+	for _, c := range []struct {
+		method, body string
+		status       int
+	}{
+		{"POST", `{"name": "squat", "reps": 2, "duration": 1000000000, "position": "standing",
+			"modality": "strength", "focus": ["knee"], "effort": "high"}`, http.StatusConflict},
+		{"PUT", `{"name": "handstand", "reps": 2, "duration": 1000000000, "position": "standing",
+			"modality": "strength", "focus": ["wrist"], "effort": "high"}`, http.StatusNotFound},
+		{"POST", `{"name": "../etc", "reps": 2}`, http.StatusBadRequest},
+		{"POST", `{"name": "plank", "reps": 3, "duration": 20, "position": "ground",
+			"modality": "strength", "focus": ["back"], "effort": "high"}`, http.StatusBadRequest},
+	} {
+		if code := fetchStatus(t, movements, cookie, c.method, "/admin/movements", c.body); code != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.body, c.status, code)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// loadPreferences returns the user's stored preferences, or the defaults if
+// they have never set any.
+func loadPreferences(username string) (model.WorkoutPreferences, error) {
+	preferences, err := store.Preferences(username)
+	if errors.Is(err, ErrNotFound) {
+		return model.DefaultWorkoutPreferences(), nil
+	}
+	return preferences, err
+}
+
+func makePreferencesHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			preferences, err := loadPreferences(session.Username)
+			if err != nil {
+				log.Println("ERROR loading preferences", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(preferences); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "PUT":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			var preferences model.WorkoutPreferences
+			if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+				log.Println("Bad request", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if err := preferences.Validate(); err != nil {
+				log.Println("Invalid preferences", err)
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			if err := store.PutPreferences(session.Username, preferences); err != nil {
+				log.Println("ERROR storing preferences", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
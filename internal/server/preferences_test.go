@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestPreferencesHandler(t *testing.T) {
+	cookie := newTestSession(t)
+	handler := makePreferencesHandler()
+
+	var preferences model.WorkoutPreferences
+	if err := json.NewDecoder(serve(t, handler, cookie, "GET", "/preferences", "").Body).Decode(&preferences); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(preferences, model.DefaultWorkoutPreferences()) {
+		t.Errorf("expected the defaults before any are set, got %+v", preferences)
+	}
+
+	preferences.Focus = []model.Focus{model.Hip}
+	preferences.Effort.RepMultiplier = 1.5
+	body, _ := json.Marshal(preferences)
+	serve(t, handler, cookie, "PUT", "/preferences", string(body))
+	var stored model.WorkoutPreferences
+	if err := json.NewDecoder(serve(t, handler, cookie, "GET", "/preferences", "").Body).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored, preferences) {
+		t.Errorf("expected the preferences put to be returned, got %+v", stored)
+	}
+
+	preferences.Structure.MinWarmupRatio = 2
+	body, _ = json.Marshal(preferences)
+	if code := fetchStatus(t, handler, cookie, "PUT", "/preferences", string(body)); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid preferences, got %d", code)
+	}
+	if current, _ := loadPreferences("alice"); !reflect.DeepEqual(current, stored) {
+		t.Errorf("invalid preferences were stored, got %+v", current)
+	}
+}
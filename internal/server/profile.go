@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// loadProfile returns the user's stored injury profile, or an empty profile
+// if they have never set one.
+func loadProfile(username string) (model.Profile, error) {
+	profile, err := store.Profile(username)
+	if errors.Is(err, ErrNotFound) {
+		return model.Profile{Limitations: []model.Contraindication{}}, nil
+	}
+	return profile, err
+}
+
+func makeProfileHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			profile, err := loadProfile(session.Username)
+			if err != nil {
+				log.Println("ERROR loading profile", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(profile); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "PUT":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			var profile model.Profile
+			if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+				log.Println("Bad request", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if err := profile.Validate(); err != nil {
+				log.Println("Invalid profile", err)
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			if err := store.PutProfile(session.Username, profile); err != nil {
+				log.Println("ERROR storing profile", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
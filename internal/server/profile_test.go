@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestProfileStandingOnlyWorkout(t *testing.T) {
+	cookie := newTestSession(t)
+	serve(t, makeProfileHandler(), cookie, "PUT", "/profile", `{"limitations": ["ground work"]}`)
+	var profile model.Profile
+	if err := json.NewDecoder(serve(t, makeProfileHandler(), cookie, "GET", "/profile", "").Body).Decode(&profile); err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Limitations) != 1 || profile.Limitations[0] != model.GroundWork {
+		t.Fatalf("profile was not stored, got %+v", profile)
+	}
+	for seed := 0; seed < 5; seed++ {
+		var workout model.Workout
+		body := serve(t, makeFetchWorkoutHandler(), cookie, "POST", fmt.Sprintf("/workout?seed=%d", seed), `"4/1/2024"`).Body
+		if err := json.NewDecoder(body).Decode(&workout); err != nil {
+			t.Fatal(err)
+		}
+		for _, movement := range workout.Movements {
+			if movement.Position == model.Ground {
+				t.Fatalf("seed %d: ground movement %s was selected", seed, movement.Name)
+			}
+		}
+	}
+}
+
+func TestProfileRejectsUnknownLimitation(t *testing.T) {
+	cookie := newTestSession(t)
+	code := fetchStatus(t, makeProfileHandler(), cookie, "PUT", "/profile", `{"limitations": ["no jumping"]}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown limitation, got %d", code)
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// ProgramStatus is a user's enrollment and what it schedules today.
+type ProgramStatus struct {
+	Enrollment model.Enrollment `json:"enrollment"`
+	// Today is nil before the program starts and after it ends.
+	Today *model.ScheduledDay `json:"today,omitempty"`
+}
+
+// scheduledDay returns the session the user's program schedules on the day,
+// reporting false if they aren't enrolled in a running program.
+func scheduledDay(username string, day time.Time) (model.ScheduledDay, bool) {
+	enrollment, err := store.Enrollment(username)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Println("ERROR loading enrollment for", username, err)
+		}
+		return model.ScheduledDay{}, false
+	}
+	scheduled, err := enrollment.Schedule(day)
+	if err != nil {
+		if !errors.Is(err, model.ErrProgramNotStarted) && !errors.Is(err, model.ErrProgramOver) {
+			log.Println("ERROR scheduling", enrollment.Program, "for", username, err)
+		}
+		return model.ScheduledDay{}, false
+	}
+	return scheduled, true
+}
+
+// isRestDay reports whether the workout is a program's scheduled rest day.
+func isRestDay(workout model.Workout) bool {
+	return workout.Scheduled != nil && workout.Scheduled.Rest
+}
+
+func makeProgramsHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if session := GetSession(w, r); session == nil {
+				return
+			}
+			if err := json.NewEncoder(w).Encode(model.Programs()); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
+
+// makeProgramHandler manages the user's enrollment. GET returns it with
+// today's session, POST ?name= enrolls in a program starting today or on
+// ?start=, and DELETE unenrolls.
+func makeProgramHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "POST" && r.Method != "DELETE" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		session := GetSession(w, r)
+		if session == nil {
+			return
+		}
+		now := time.Now()
+		switch r.Method {
+		case "POST":
+			enrollment := model.Enrollment{Program: r.URL.Query().Get("name"),
+				Start: r.URL.Query().Get("start")}
+			if enrollment.Start == "" {
+				enrollment.Start = now.Format(model.DateLayout)
+			}
+			if err := enrollment.Validate(); errors.Is(err, model.ErrUnknownProgram) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(err.Error()))
+				return
+			} else if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			if err := store.PutEnrollment(session.Username, enrollment); err != nil {
+				log.Println("ERROR storing enrollment", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		case "DELETE":
+			if err := store.DeleteEnrollment(session.Username); err != nil {
+				log.Println("ERROR deleting enrollment", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+		enrollment, err := store.Enrollment(session.Username)
+		if errors.Is(err, ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Not enrolled in a program"))
+			return
+		} else if err != nil {
+			log.Println("ERROR loading enrollment", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		status := ProgramStatus{Enrollment: enrollment}
+		if today, ok := scheduledDay(session.Username, now); ok {
+			status.Today = &today
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestProgramEnrollment(t *testing.T) {
+	cookie := newTestSession(t)
+	program := makeProgramHandler()
+	var programs []model.Program
+	if err := json.NewDecoder(serve(t, makeProgramsHandler(), cookie, "GET", "/programs", "").Body).Decode(&programs); err != nil {
+		t.Fatal(err)
+	}
+	if len(programs) == 0 {
+		t.Fatal("expected programs to enroll in")
+	}
+	if code := fetchStatus(t, program, cookie, "GET", "/program", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 before enrolling, got %d", code)
+	}
+	if code := fetchStatus(t, program, cookie, "POST", "/program?name=yoga", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown program, got %d", code)
+	}
+	if code := fetchStatus(t, program, cookie, "POST", "/program?name=foundations&start=April", ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad start date, got %d", code)
+	}
+	var status ProgramStatus
+	w := serve(t, program, cookie, "POST", "/program?name=foundations&start=2024-04-01", "")
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Enrollment.Program != "foundations" || status.Today != nil {
+		t.Errorf("expected an enrollment in a finished program, got %+v", status)
+	}
+	serve(t, program, cookie, "DELETE", "/program", "")
+	if code := fetchStatus(t, program, cookie, "GET", "/program", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 after unenrolling, got %d", code)
+	}
+}
+
+func TestFetchScheduledWorkout(t *testing.T) {
+	cookie := newTestSession(t)
+	serve(t, makeProgramHandler(), cookie, "POST", "/program?name=foundations&start=2024-04-01", "")
+	fetch := makeFetchWorkoutHandler()
+
+	var workout model.Workout
+	// April 3rd is week one's Wednesday, a circuit.
+	if err := json.NewDecoder(serve(t, fetch, cookie, "POST", "/workout?format=amrap", `"4/3/2024"`).Body).Decode(&workout); err != nil {
+		t.Fatal(err)
+	}
+	if workout.Format != model.Circuit || workout.Scheduled == nil || workout.Scheduled.Week != 1 {
+		t.Errorf("expected week one's circuit, got %s %+v", workout.Format, workout.Scheduled)
+	}
+
+	workout = model.Workout{}
+	if err := json.NewDecoder(serve(t, fetch, cookie, "POST", "/workout", `"4/2/2024"`).Body).Decode(&workout); err != nil {
+		t.Fatal(err)
+	}
+	if len(workout.Movements) != 0 || workout.Scheduled == nil || !workout.Scheduled.Rest {
+		t.Errorf("expected a rest day, got %+v", workout)
+	}
+	if session, _ := store.UserSession("alice"); !session.DoneForTheDay {
+		t.Error("expected a rest day to be done for the day")
+	}
+	serve(t, fetch, cookie, "POST", "/workout", `"4/2/2024"`)
+	records, err := store.History("alice", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rests := 0
+	for _, record := range records {
+		if isRestDay(record.Workout) {
+			rests++
+		}
+	}
+	if rests != 1 {
+		t.Errorf("expected the rest day to be archived once, got %d", rests)
+	}
+}
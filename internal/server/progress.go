@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// loadProgress returns the user's progress, empty if they have none.
+func loadProgress(username string) (model.Progress, error) {
+	progress, err := store.Progress(username)
+	if errors.Is(err, ErrNotFound) {
+		return model.NewProgress(), nil
+	}
+	return progress, err
+}
+
+// recordProgress counts the movements completed in the session towards
+// promoting the user past them.
+func recordProgress(session *UserSession) {
+	if len(session.Completed) == 0 {
+		return
+	}
+	progress, err := loadProgress(session.Username)
+	if err != nil {
+		log.Println("ERROR loading progress for", session.Username, err)
+		return
+	}
+	bank, err := model.Movements()
+	if err != nil {
+		log.Println("ERROR loading movements", err)
+		return
+	}
+	names := []string{}
+	for _, completion := range session.Completed {
+		names = append(names, completion.Movement)
+	}
+	day := session.WorkoutDay
+	if parsed, ok := parseWorkoutDay(session.WorkoutDay, session.StartedAt); ok {
+		day = parsed.Format(model.DateLayout)
+	}
+	if promoted := progress.Complete(bank, day, names); len(promoted) > 0 {
+		log.Println(session.Username, "promoted past", promoted)
+	}
+	if err := store.PutProgress(session.Username, progress); err != nil {
+		log.Println("ERROR storing progress for", session.Username, err)
+	}
+}
+
+func makeProgressHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			progress, err := loadProgress(session.Username)
+			if err != nil {
+				log.Println("ERROR loading progress", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(progress); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
+
+// makeStepBackHandler returns the user from the movement ?name= to the
+// movements they were promoted from.
+func makeStepBackHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			progress, err := loadProgress(session.Username)
+			if err != nil {
+				log.Println("ERROR loading progress", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			bank, err := model.Movements()
+			if err != nil {
+				log.Println("ERROR loading movements", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := progress.StepBack(bank, r.URL.Query().Get("name")); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			}
+			if err := store.PutProgress(session.Username, progress); err != nil {
+				log.Println("ERROR storing progress", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(progress); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
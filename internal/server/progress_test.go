@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestProgressPromotesAcrossSessions(t *testing.T) {
+	cookie := newTestSession(t)
+	for i := 0; i < model.PromoteAfter; i++ {
+		session := &UserSession{Username: "alice", WorkoutDay: fmt.Sprintf("2024-04-%02d", i+1),
+			Completed: []Completion{{Movement: "sit to stand"}, {Movement: "sit to stand"}}}
+		recordProgress(session)
+		// Another workout the same day isn't another session.
+		recordProgress(session)
+		if progress, _ := loadProgress("alice"); i < model.PromoteAfter-1 && len(progress.Mastered) != 0 {
+			t.Fatalf("promoted after %d days", i+1)
+		}
+	}
+	var progress model.Progress
+	if err := json.NewDecoder(serve(t, makeProgressHandler(), cookie, "GET", "/progress", "").Body).Decode(&progress); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress.Mastered) != 1 || progress.Mastered[0] != "sit to stand" {
+		t.Fatalf("expected sit to stand to be mastered, got %+v", progress)
+	}
+
+	target := "/progress/back?name=" + url.QueryEscape("staggered sit to stand")
+	serve(t, makeStepBackHandler(), cookie, "POST", target, "")
+	if progress, _ := loadProgress("alice"); len(progress.Mastered) != 0 {
+		t.Errorf("expected stepping back to unmaster sit to stand, got %v", progress.Mastered)
+	}
+	if code := fetchStatus(t, makeStepBackHandler(), cookie, "POST", target, ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 stepping back twice, got %d", code)
+	}
+}
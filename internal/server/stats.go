@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+const (
+	defaultStatsWindowDays = 28
+	maxStatsWindowDays     = 366
+	// maxStatsRecords bounds how much history is read to compute stats.
+	maxStatsRecords = 5000
+)
+
+// workoutDayLayouts are the toLocaleDateString formats clients send as their
+// WorkoutDay.
+var workoutDayLayouts = []string{"1/2/2006", "2/1/2006", "2006-01-02",
+	"2006/1/2", "2.1.2006", "2-1-2006"}
+
+type (
+	// Stats summarize a user's adherence and training volume.
+	Stats struct {
+		// CurrentStreak counts consecutive days done, ending today or
+		// yesterday. A program's rest days don't break a streak.
+		CurrentStreak int `json:"currentStreak"`
+		LongestStreak int `json:"longestStreak"`
+		// The remaining stats only consider the last WindowDays days.
+		WindowDays      int                              `json:"windowDays"`
+		WorkoutsPerWeek float64                          `json:"workoutsPerWeek"`
+		TotalActiveTime time.Duration                    `json:"totalActiveTime"`
+		FocusVolume     map[model.Focus]time.Duration    `json:"focusVolume"`
+		ModalityVolume  map[model.Modality]time.Duration `json:"modalityVolume"`
+	}
+)
+
+// parseWorkoutDay parses a client's WorkoutDay. Locale formats are ambiguous
+// between day and month first so the parse nearest to when the workout was
+// started wins.
+func parseWorkoutDay(workoutDay string, startedAt time.Time) (time.Time, bool) {
+	var best time.Time
+	found := false
+	for _, layout := range workoutDayLayouts {
+		day, err := time.Parse(layout, workoutDay)
+		if err != nil {
+			continue
+		}
+		if !found || startedAt.IsZero() ||
+			absDuration(day.Sub(startedAt)) < absDuration(best.Sub(startedAt)) {
+			best, found = day, true
+		}
+	}
+	return best, found
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// completedMovement finds the workout movement a completion is for. Records
+// from before completions kept their index are matched by name.
+func completedMovement(workout model.Workout, completion Completion) (model.Movement, bool) {
+	if completion.Index < len(workout.Movements) &&
+		workout.Movements[completion.Index].Name == completion.Movement {
+		return workout.Movements[completion.Index], true
+	}
+	for _, movement := range workout.Movements {
+		if movement.Name == completion.Movement {
+			return movement, true
+		}
+	}
+	return model.Movement{}, false
+}
+
+// computeStats over a user's records as of today, a date at midnight UTC.
+func computeStats(records []WorkoutRecord, today time.Time, windowDays int) Stats {
+	stats := Stats{
+		WindowDays:     windowDays,
+		FocusVolume:    map[model.Focus]time.Duration{},
+		ModalityVolume: map[model.Modality]time.Duration{},
+	}
+	windowStart := today.AddDate(0, 0, 1-windowDays)
+	doneDays, restDays := map[time.Time]bool{}, map[time.Time]bool{}
+	for _, record := range records {
+		day, ok := parseWorkoutDay(record.WorkoutDay, record.StartedAt)
+		if !ok {
+			continue
+		}
+		if isRestDay(record.Workout) {
+			restDays[day] = true
+			continue
+		}
+		if record.DoneForTheDay {
+			doneDays[day] = true
+		}
+		if day.Before(windowStart) || day.After(today) {
+			continue
+		}
+		for _, completion := range record.Completed {
+			movement, ok := completedMovement(record.Workout, completion)
+			if !ok {
+				continue
+			}
+			active := movement.EstimateDuration()
+			stats.TotalActiveTime += active
+			stats.ModalityVolume[movement.Modality] += active
+			for _, focus := range movement.Focus {
+				stats.FocusVolume[focus] += active
+			}
+		}
+	}
+	days := make([]time.Time, 0, len(doneDays)+len(restDays))
+	windowWorkouts := 0
+	for day := range doneDays {
+		days = append(days, day)
+		if !day.Before(windowStart) && !day.After(today) {
+			windowWorkouts++
+		}
+	}
+	for day := range restDays {
+		if !doneDays[day] {
+			days = append(days, day)
+		}
+	}
+	stats.WorkoutsPerWeek = float64(windowWorkouts) * 7 / float64(windowDays)
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	// Rest days carry a streak over without adding to it.
+	streak := 0
+	for i, day := range days {
+		if i > 0 && day.Sub(days[i-1]) != 24*time.Hour {
+			streak = 0
+		}
+		if doneDays[day] {
+			streak++
+		}
+		stats.LongestStreak = max(stats.LongestStreak, streak)
+	}
+	if len(days) > 0 {
+		last := days[len(days)-1]
+		if last.Equal(today) || last.Equal(today.AddDate(0, 0, -1)) {
+			stats.CurrentStreak = streak
+		}
+	}
+	return stats
+}
+
+func makeStatsHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			session := GetSession(w, r)
+			if session == nil {
+				return
+			}
+			windowDays := defaultStatsWindowDays
+			if v := r.URL.Query().Get("days"); v != "" {
+				days, err := strconv.Atoi(v)
+				if err != nil || days <= 0 || days > maxStatsWindowDays {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte("Invalid days"))
+					return
+				}
+				windowDays = days
+			}
+			records, err := store.History(session.Username, 0, maxStatsRecords)
+			if err != nil {
+				log.Println("ERROR loading history", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !session.DoneForTheDay && len(session.Completed) > 0 {
+				// Count today's progress before it is archived.
+				records = append(records, session.record())
+			}
+			// The client's day may be ahead of the server's.
+			now := time.Now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+			if clientDay, ok := parseWorkoutDay(session.WorkoutDay, session.StartedAt); ok && clientDay.After(today) {
+				today = clientDay
+			}
+			stats := computeStats(records, today, windowDays)
+			if err := json.NewEncoder(w).Encode(stats); err != nil {
+				log.Println(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestParseWorkoutDayPrefersNearestToStart(t *testing.T) {
+	startedAt := time.Date(2024, time.January, 4, 18, 0, 0, 0, time.UTC)
+	day, ok := parseWorkoutDay("4/1/2024", startedAt)
+	if !ok || !day.Equal(time.Date(2024, time.January, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected day first parse, got %s", day)
+	}
+	day, ok = parseWorkoutDay("2024-01-04", time.Time{})
+	if !ok || day.Day() != 4 {
+		t.Errorf("expected ISO parse, got %s", day)
+	}
+	if _, ok := parseWorkoutDay("someday", startedAt); ok {
+		t.Error("expected an unparseable day to be rejected")
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	squat := model.Movement{Name: "squat", Reps: 2, Duration: 3 * time.Second,
+		Modality: model.Strength, Focus: []model.Focus{model.Hip, model.Knee}}
+	stretch := model.Movement{Name: "stretch", Reps: 1, Duration: 8 * time.Second,
+		Modality: model.Mobility, Focus: []model.Focus{model.Back}}
+	done := func(day string) WorkoutRecord {
+		return WorkoutRecord{WorkoutDay: day, DoneForTheDay: true,
+			Workout:   model.Workout{Movements: []model.Movement{squat, stretch}, Done: 2},
+			Completed: []Completion{{Movement: "squat"}, {Movement: "stretch", Index: 1}}}
+	}
+	partial := WorkoutRecord{WorkoutDay: "2024-03-10",
+		Workout:   model.Workout{Movements: []model.Movement{squat, stretch}, Done: 1},
+		Completed: []Completion{{Movement: "squat"}}}
+	records := []WorkoutRecord{done("2024-02-01"), done("2024-02-02"), done("2024-02-03"),
+		done("2024-03-08"), done("2024-03-09"), partial, done("2024-03-11")}
+	today := time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC)
+	stats := computeStats(records, today, 7)
+	if stats.LongestStreak != 3 {
+		t.Errorf("expected longest streak 3, got %d", stats.LongestStreak)
+	}
+	if stats.CurrentStreak != 1 {
+		t.Errorf("expected current streak 1, got %d", stats.CurrentStreak)
+	}
+	if stats.WorkoutsPerWeek != 3 {
+		t.Errorf("expected 3 workouts per week, got %f", stats.WorkoutsPerWeek)
+	}
+	squatTime, stretchTime := squat.EstimateDuration(), stretch.EstimateDuration()
+	if want := 3*(squatTime+stretchTime) + squatTime; stats.TotalActiveTime != want {
+		t.Errorf("expected %s active, got %s", want, stats.TotalActiveTime)
+	}
+	if want := 4 * squatTime; stats.FocusVolume[model.Knee] != want {
+		t.Errorf("expected %s of knee volume, got %s", want, stats.FocusVolume[model.Knee])
+	}
+	if want := 3 * stretchTime; stats.ModalityVolume[model.Mobility] != want {
+		t.Errorf("expected %s of mobility volume, got %s", want, stats.ModalityVolume[model.Mobility])
+	}
+	if stats := computeStats(records, today.AddDate(0, 0, 2), 7); stats.CurrentStreak != 0 {
+		t.Errorf("expected the streak to be broken, got %d", stats.CurrentStreak)
+	}
+}
+
+func TestComputeStatsAfterEndedBlock(t *testing.T) {
+	squat := model.Movement{Name: "squat", Reps: 2, Duration: 3 * time.Second}
+	lunge := model.Movement{Name: "lunge", Reps: 2, Duration: 5 * time.Second}
+	stretch := model.Movement{Name: "stretch", Reps: 1, Duration: 8 * time.Second}
+	// The AMRAP's padded lunge was skipped by ending the block.
+	record := WorkoutRecord{WorkoutDay: "2024-03-11", DoneForTheDay: true,
+		Workout:   model.Workout{Movements: []model.Movement{squat, lunge, stretch}, Done: 3},
+		Completed: []Completion{{Movement: "squat"}, {Movement: "stretch", Index: 2}}}
+	today := time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC)
+	stats := computeStats([]WorkoutRecord{record}, today, 7)
+	if want := squat.EstimateDuration() + stretch.EstimateDuration(); stats.TotalActiveTime != want {
+		t.Errorf("expected %s active, got %s", want, stats.TotalActiveTime)
+	}
+	record.Completed[1].Index = 0
+	if stats := computeStats([]WorkoutRecord{record}, today, 7); stats.TotalActiveTime !=
+		squat.EstimateDuration()+stretch.EstimateDuration() {
+		t.Errorf("expected a completion without its index to match by name, got %s",
+			stats.TotalActiveTime)
+	}
+}
+
+func TestComputeStatsStreakAcrossRestDay(t *testing.T) {
+	done := func(day string) WorkoutRecord {
+		return WorkoutRecord{WorkoutDay: day, DoneForTheDay: true}
+	}
+	rest := WorkoutRecord{WorkoutDay: "2024-04-02", DoneForTheDay: true,
+		Workout: model.Workout{Scheduled: &model.ScheduledDay{ProgramDay: model.ProgramDay{Rest: true}}}}
+	records := []WorkoutRecord{done("2024-04-01"), rest, done("2024-04-03")}
+	stats := computeStats(records, time.Date(2024, time.April, 3, 0, 0, 0, 0, time.UTC), 7)
+	if stats.CurrentStreak != 2 || stats.LongestStreak != 2 {
+		t.Errorf("expected the rest day to carry a streak of 2, got %d and %d",
+			stats.CurrentStreak, stats.LongestStreak)
+	}
+	if want := 2.0; stats.WorkoutsPerWeek != want {
+		t.Errorf("expected the rest day not to count as a workout, got %f", stats.WorkoutsPerWeek)
+	}
+	stats = computeStats(records[:2], time.Date(2024, time.April, 3, 0, 0, 0, 0, time.UTC), 7)
+	if stats.CurrentStreak != 1 {
+		t.Errorf("expected yesterday's rest day to keep the streak, got %d", stats.CurrentStreak)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+const (
+	// MemoryStore keeps everything in memory, it is lost on restart.
+	MemoryStore = "memory"
+	// BoltStore keeps everything in a bbolt file on disk.
+	BoltStore = "bolt"
+
+	// RoleAdmin may use the /admin endpoints.
+	RoleAdmin = "admin"
+
+	// Sessions expire after a month without use.
+	sessionTTL         = 30 * 24 * time.Hour
+	sessionGCFrequency = time.Hour
+)
+
+var (
+	// ErrNotFound is returned when a store has no value for a key.
+	ErrNotFound = errors.New("not found")
+	// ErrExists is returned when adding a key that is already taken.
+	ErrExists = errors.New("already exists")
+)
+
+type (
+	// Store persists users, their sessions, workouts and history.
+	Store interface {
+		// AddUser stores a user's hashed credentials, returning ErrExists if
+		// the username is taken.
+		AddUser(username string, key []byte) error
+		// UserKey returns a user's hashed credentials.
+		UserKey(username string) ([]byte, error)
+		// AddSession maps a session token to a user.
+		AddSession(token, username string) error
+		// SessionUser returns the user for a session token and refreshes
+		// the session's TTL.
+		SessionUser(token string) (string, error)
+		// SessionCount returns the number of live sessions.
+		SessionCount() int
+		// UserSession returns a user's in-progress workout state.
+		UserSession(username string) (UserSession, error)
+		// PutUserSession stores a user's in-progress workout state.
+		PutUserSession(session UserSession) error
+		// Preferences returns a user's workout preferences.
+		Preferences(username string) (model.WorkoutPreferences, error)
+		// PutPreferences stores a user's workout preferences.
+		PutPreferences(username string, preferences model.WorkoutPreferences) error
+		// AddHistory appends a finished or replaced workout to a user's
+		// history.
+		AddHistory(username string, record WorkoutRecord) error
+		// History returns up to limit of a user's past workouts, newest
+		// first, skipping the newest offset.
+		History(username string, offset, limit int) ([]WorkoutRecord, error)
+		// AddSwap records a movement a user swapped out of a workout.
+		AddSwap(username string, swap Swap) error
+		// SwapCounts returns how often a user swapped out each movement
+		// before a time.
+		SwapCounts(username string, before time.Time) (map[string]int, error)
+		// MovementLists returns a user's blocked and favorite movements.
+		MovementLists(username string) (MovementLists, error)
+		// PutMovementLists stores a user's blocked and favorite movements.
+		PutMovementLists(username string, lists MovementLists) error
+		// Profile returns a user's injury profile.
+		Profile(username string) (model.Profile, error)
+		// PutProfile stores a user's injury profile.
+		PutProfile(username string, profile model.Profile) error
+		// Progress returns a user's level along the movement progressions.
+		Progress(username string) (model.Progress, error)
+		// PutProgress stores a user's level along the movement progressions.
+		PutProgress(username string, progress model.Progress) error
+		// Enrollment returns the program a user is enrolled in.
+		Enrollment(username string) (model.Enrollment, error)
+		// PutEnrollment enrolls a user in a program.
+		PutEnrollment(username string, enrollment model.Enrollment) error
+		// DeleteEnrollment unenrolls a user from their program.
+		DeleteEnrollment(username string) error
+		// Timings returns how a user's measured sessions compare to the
+		// estimates.
+		Timings(username string) (model.Timings, error)
+		// UpdateTimings replaces a user's timings with update applied to
+		// them, atomically. A user without timings starts from none.
+		UpdateTimings(username string, update func(model.Timings) model.Timings) error
+		// UserRole returns a user's role, ErrNotFound if they have none.
+		UserRole(username string) (string, error)
+		// SetUserRole grants a user a role.
+		SetUserRole(username, role string) error
+		// Movements returns the movements managed through the admin API.
+		Movements() ([]model.Movement, error)
+		// PutMovement adds or replaces a managed movement by name.
+		PutMovement(movement model.Movement) error
+		// MovementImage returns a managed movement's png for an iteration.
+		MovementImage(name, iteration string) ([]byte, error)
+		// PutMovementImage stores a managed movement's png for an iteration.
+		PutMovementImage(name, iteration string, image []byte) error
+		// Close releases the store's resources.
+		Close() error
+	}
+)
+
+// NewStore opens the store of the given kind, path is only used by stores
+// that persist to disk.
+func NewStore(kind, path string) (Store, error) {
+	switch kind {
+	case "", MemoryStore:
+		return NewMemoryStore(), nil
+	case BoltStore:
+		return NewBoltStore(path)
+	}
+	return nil, errors.New("unknown store " + kind)
+}
+
+// imageKey identifies a movement's image for an iteration.
+func imageKey(name, iteration string) string {
+	return name + "/" + iteration
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "gofit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	return map[string]Store{MemoryStore: NewMemoryStore(), BoltStore: bolt}
+}
+
+func TestStoreUsersAndSessions(t *testing.T) {
+	for name, s := range testStores(t) {
+		if err := s.AddUser("alice", []byte("key")); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := s.AddUser("alice", []byte("other")); !errors.Is(err, ErrExists) {
+			t.Errorf("%s: expected ErrExists, got %v", name, err)
+		}
+		if key, err := s.UserKey("alice"); err != nil || string(key) != "key" {
+			t.Errorf("%s: expected stored key, got %q %v", name, key, err)
+		}
+		if _, err := s.UserKey("bob"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		if err := s.AddSession("token", "alice"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if username, err := s.SessionUser("token"); err != nil || username != "alice" {
+			t.Errorf("%s: expected alice, got %q %v", name, username, err)
+		}
+		if _, err := s.SessionUser("missing"); err == nil {
+			t.Errorf("%s: expected an error for a missing session", name)
+		}
+		if count := s.SessionCount(); count != 1 {
+			t.Errorf("%s: expected 1 session, got %d", name, count)
+		}
+	}
+}
+
+func TestStoreWorkoutsAndHistory(t *testing.T) {
+	for name, s := range testStores(t) {
+		session, err := s.UserSession("alice")
+		if err != nil || session.Username != "alice" {
+			t.Fatalf("%s: expected a fresh session, got %+v %v", name, session, err)
+		}
+		session.WorkoutDay = "2024-04-01"
+		session.Workout = model.Workout{Movements: []model.Movement{{Name: "squat"}}, Done: 1}
+		if err := s.PutUserSession(session); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if stored, _ := s.UserSession("alice"); stored.Workout.Done != 1 || stored.WorkoutDay != "2024-04-01" {
+			t.Errorf("%s: session was not stored, got %+v", name, stored)
+		}
+		if _, err := s.Preferences("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		preferences := model.DefaultWorkoutPreferences()
+		preferences.Focus = []model.Focus{model.Shoulder}
+		if err := s.PutPreferences("alice", preferences); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if stored, _ := s.Preferences("alice"); len(stored.Focus) != 1 {
+			t.Errorf("%s: preferences were not stored, got %+v", name, stored)
+		}
+		for _, day := range []string{"2024-04-01", "2024-04-02", "2024-04-03"} {
+			if err := s.AddHistory("alice", WorkoutRecord{WorkoutDay: day}); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		records, err := s.History("alice", 1, 5)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(records) != 2 || records[0].WorkoutDay != "2024-04-02" || records[1].WorkoutDay != "2024-04-01" {
+			t.Errorf("%s: unexpected history page %+v", name, records)
+		}
+		if records, _ := s.History("bob", 0, 5); len(records) != 0 {
+			t.Errorf("%s: expected no history for bob, got %+v", name, records)
+		}
+	}
+}
+
+func TestBoltStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gofit.db")
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddUser("alice", []byte("key"))
+	s.AddSession("token", "alice")
+	s.Close()
+	s, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if username, err := s.SessionUser("token"); err != nil || username != "alice" {
+		t.Errorf("expected session to survive a restart, got %q %v", username, err)
+	}
+}
+
+func TestStoreRolesAndMovements(t *testing.T) {
+	for name, s := range testStores(t) {
+		if _, err := s.UserRole("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		s.SetUserRole("alice", RoleAdmin)
+		if role, _ := s.UserRole("alice"); role != RoleAdmin {
+			t.Errorf("%s: expected admin role, got %q", name, role)
+		}
+		s.PutMovement(model.Movement{Name: "plank", Reps: 1})
+		s.PutMovement(model.Movement{Name: "plank", Reps: 3})
+		if movements, _ := s.Movements(); len(movements) != 1 || movements[0].Reps != 3 {
+			t.Errorf("%s: expected plank to be replaced, got %+v", name, movements)
+		}
+		if _, err := s.MovementImage("plank", "active"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		s.PutMovementImage("plank", "active", []byte("png"))
+		if image, _ := s.MovementImage("plank", "active"); string(image) != "png" {
+			t.Errorf("%s: expected stored image, got %q", name, image)
+		}
+	}
+}
+
+func TestStoreSwaps(t *testing.T) {
+	for name, s := range testStores(t) {
+		now := time.Now()
+		for i, movement := range []string{"squat", "lunge", "squat"} {
+			swap := Swap{Movement: movement, Replacement: "bridge", SwappedAt: now.Add(time.Duration(i) * time.Hour)}
+			if err := s.AddSwap("alice", swap); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		counts, err := s.SwapCounts("alice", now.Add(3*time.Hour))
+		if err != nil || counts["squat"] != 2 || counts["lunge"] != 1 {
+			t.Errorf("%s: unexpected swap counts %v %v", name, counts, err)
+		}
+		if counts, _ := s.SwapCounts("alice", now.Add(time.Hour)); counts["squat"] != 1 || counts["lunge"] != 0 {
+			t.Errorf("%s: expected only the first swap before an hour, got %v", name, counts)
+		}
+		if counts, _ := s.SwapCounts("bob", now); len(counts) != 0 {
+			t.Errorf("%s: expected no swaps for bob, got %v", name, counts)
+		}
+	}
+}
+
+func TestStoreMovementLists(t *testing.T) {
+	for name, s := range testStores(t) {
+		if _, err := s.MovementLists("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		lists := MovementLists{Blocked: []string{"lunge"}, Favorites: []string{"bridge"}}
+		if err := s.PutMovementLists("alice", lists); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if stored, _ := s.MovementLists("alice"); len(stored.Blocked) != 1 || stored.Favorites[0] != "bridge" {
+			t.Errorf("%s: lists were not stored, got %+v", name, stored)
+		}
+	}
+}
+
+func TestStoreProfile(t *testing.T) {
+	for name, s := range testStores(t) {
+		if _, err := s.Profile("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		profile := model.Profile{Limitations: []model.Contraindication{model.GroundWork}}
+		if err := s.PutProfile("alice", profile); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if stored, _ := s.Profile("alice"); len(stored.Limitations) != 1 || stored.Limitations[0] != model.GroundWork {
+			t.Errorf("%s: profile was not stored, got %+v", name, stored)
+		}
+	}
+}
+
+func TestStoreProgress(t *testing.T) {
+	for name, s := range testStores(t) {
+		if _, err := s.Progress("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		progress := model.Progress{Sessions: map[string]int{"bridge": 2}, Mastered: []string{"sit to stand"}}
+		if err := s.PutProgress("alice", progress); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if stored, _ := s.Progress("alice"); stored.Sessions["bridge"] != 2 || stored.Mastered[0] != "sit to stand" {
+			t.Errorf("%s: progress was not stored, got %+v", name, stored)
+		}
+	}
+}
+
+func TestStoreEnrollment(t *testing.T) {
+	for name, s := range testStores(t) {
+		if _, err := s.Enrollment("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		if err := s.PutEnrollment("alice", model.Enrollment{Program: "mobility", Start: "2024-04-01"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if stored, _ := s.Enrollment("alice"); stored.Program != "mobility" {
+			t.Errorf("%s: enrollment was not stored, got %+v", name, stored)
+		}
+		if err := s.DeleteEnrollment("alice"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := s.Enrollment("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound after deleting, got %v", name, err)
+		}
+	}
+}
+
+func TestStoreTimings(t *testing.T) {
+	for name, s := range testStores(t) {
+		if _, err := s.Timings("alice"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		for i := 0; i < 2; i++ {
+			err := s.UpdateTimings("alice", func(timings model.Timings) model.Timings {
+				timings.User.Pace = timings.User.Pace.Observe(1.5)
+				timings.Movements = map[string]model.Timing{"squat": {Ratio: 0.8, Samples: i + 1}}
+				return timings
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		timings, err := s.Timings("alice")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if timings.User.Pace.Samples != 2 || timings.Movements["squat"].Samples != 2 {
+			t.Errorf("%s: expected both updates, got %+v", name, timings)
+		}
+		if _, err := s.Timings("bob"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected bob's timings to be separate, got %v", name, err)
+		}
+	}
+}
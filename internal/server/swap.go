@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+type (
+	// Swap records a movement a user swapped out of a workout.
+	Swap struct {
+		Movement    string    `json:"movement"`
+		Replacement string    `json:"replacement"`
+		SwappedAt   time.Time `json:"swappedAt"`
+	}
+
+	swapRequest struct {
+		// Index of the movement to replace in the current workout.
+		Index int `json:"index"`
+	}
+)
+
+// swapCounts returns how often the user swapped out each movement before a
+// time, or nil if they can't be loaded.
+func swapCounts(username string, before time.Time) map[string]int {
+	counts, err := store.SwapCounts(username, before)
+	if err != nil {
+		log.Println("ERROR loading swaps for", username, err)
+		return nil
+	}
+	return counts
+}
+
+func makeSwapHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			session, unlock := lockSession(w, r)
+			if session == nil {
+				return
+			}
+			defer unlock()
+			var request swapRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Invalid swap request"))
+				return
+			}
+			day, ok := parseWorkoutDay(session.WorkoutDay, session.StartedAt)
+			if !ok {
+				day = time.Now()
+			}
+			options, err := loadWorkoutOptions(session.Username, day)
+			if err != nil {
+				log.Println("ERROR loading workout options", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var old string
+			if request.Index >= 0 && request.Index < len(session.Workout.Movements) {
+				old = session.Workout.Movements[request.Index].Name
+			}
+			replacement, err := session.Workout.Swap(request.Index, options)
+			if errors.Is(err, model.ErrSwapIndex) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(err.Error()))
+				return
+			} else if err != nil {
+				writeWorkoutError(w, err)
+				return
+			}
+			err = store.AddSwap(session.Username, Swap{Movement: old,
+				Replacement: replacement.Name, SwappedAt: time.Now()})
+			if err != nil {
+				log.Println("ERROR storing swap for", session.Username, err)
+			}
+			if !putSession(w, session) {
+				return
+			}
+			if err := json.NewEncoder(w).Encode(session.Workout); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	return http.HandlerFunc(handler)
+}
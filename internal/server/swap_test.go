@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+func TestSwapMovement(t *testing.T) {
+	cookie := newTestSession(t)
+	serve(t, makeFetchWorkoutHandler(), cookie, "POST", "/workout?seed=5", `"4/1/2024"`)
+	before, _ := store.UserSession("alice")
+	old := before.Workout.Movements[1]
+
+	var workout model.Workout
+	body := serve(t, makeSwapHandler(), cookie, "POST", "/workout/swap", `{"index": 1}`).Body
+	if err := json.NewDecoder(body).Decode(&workout); err != nil {
+		t.Fatal(err)
+	}
+	replacement := workout.Movements[1]
+	if replacement.Name == old.Name || replacement.Position != old.Position {
+		t.Errorf("expected %s to be replaced by a %s movement, got %s %s",
+			old.Name, old.Position, replacement.Name, replacement.Position)
+	}
+	if session, _ := store.UserSession("alice"); session.Workout.Movements[1].Name != replacement.Name {
+		t.Error("swap was not saved to the session")
+	}
+	if counts, _ := store.SwapCounts("alice", time.Now().Add(time.Minute)); counts[old.Name] != 1 {
+		t.Errorf("expected the swap to be recorded, got %v", counts)
+	}
+}
+
+func TestSwapRejectsDoneMovement(t *testing.T) {
+	cookie := newTestSession(t)
+	serve(t, makeFetchWorkoutHandler(), cookie, "POST", "/workout?seed=5", `"4/1/2024"`)
+	serve(t, makeWorkoutUpdateHandler(), cookie, "POST", "/workoutUpdate", "")
+	for _, body := range []string{`{"index": 0}`, `{"index": 1000}`, `nope`} {
+		if code := fetchStatus(t, makeSwapHandler(), cookie, "POST", "/workout/swap", body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, code)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"errors"
+	"log"
+
+	"github.com/ekotlikoff/gofit/internal/model"
+)
+
+// loadTimings returns the user's timings, uncalibrated if they have none.
+func loadTimings(username string) (model.Timings, error) {
+	timings, err := store.Timings(username)
+	if errors.Is(err, ErrNotFound) {
+		return model.Timings{}, nil
+	}
+	return timings, err
+}
+
+// recordTiming learns from the measured time of the session's last
+// completed movement, and the rest before it.
+func recordTiming(session *UserSession, movement model.Movement) {
+	i := len(session.Completed) - 1
+	completion := session.Completed[i]
+	if completion.StartedAt == nil || completion.EndedAt == nil {
+		return
+	}
+	err := store.UpdateTimings(session.Username, func(timings model.Timings) model.Timings {
+		timings, _ = timings.ObserveActive(movement, completion.EndedAt.Sub(*completion.StartedAt))
+		if i > 0 && session.Completed[i-1].EndedAt != nil {
+			timings, _ = timings.ObserveRest(completion.StartedAt.Sub(*session.Completed[i-1].EndedAt))
+		}
+		return timings
+	})
+	if err != nil {
+		log.Println("ERROR storing timings for", session.Username, err)
+	}
+}
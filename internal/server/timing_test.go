@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWorkoutUpdateRecordsTimings(t *testing.T) {
+	cookie := newTestSession(t)
+	fetch, update := makeFetchWorkoutHandler(), makeWorkoutUpdateHandler()
+	serve(t, fetch, cookie, "POST", "/workout?seed=5", `"4/1/2024"`)
+	session, _ := store.UserSession("alice")
+	first := session.Workout.Movements[0]
+	start := time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(first.EstimateDuration() * 3 / 2)
+	body := fmt.Sprintf(`{"startedAt": %q, "endedAt": %q}`, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	serve(t, update, cookie, "POST", "/workoutUpdate", body)
+	start = end.Add(10 * time.Second)
+	body = fmt.Sprintf(`{"startedAt": %q, "endedAt": %q}`, start.Format(time.RFC3339),
+		start.Add(session.Workout.Movements[1].EstimateDuration()).Format(time.RFC3339))
+	serve(t, update, cookie, "POST", "/workoutUpdate", body)
+
+	timings, err := loadTimings("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing := timings.Movements[first.Name]; timing.Samples != 1 || timing.Ratio < 1.4 || timing.Ratio > 1.6 {
+		t.Errorf("expected %s to be measured at 1.5 its estimate, got %+v", first.Name, timing)
+	}
+	if timings.User.Pace.Samples != 2 || timings.User.Rest.Samples != 1 {
+		t.Errorf("expected two paces and a rest, got %+v", timings.User)
+	}
+	if bob, _ := loadTimings("bob"); len(bob.Movements) != 0 || bob.User.Pace.Samples != 0 {
+		t.Errorf("expected alice's timings not to calibrate bob's, got %+v", bob)
+	}
+	session, _ = store.UserSession("alice")
+	if session.Completed[0].StartedAt == nil || session.Completed[0].EndedAt == nil {
+		t.Errorf("expected the measured times to be stored, got %+v", session.Completed[0])
+	}
+}
+
+func TestWorkoutUpdateRejectsInvalidTimings(t *testing.T) {
+	cookie := newTestSession(t)
+	serve(t, makeFetchWorkoutHandler(), cookie, "POST", "/workout?seed=5", `"4/1/2024"`)
+	update := makeWorkoutUpdateHandler()
+	for _, body := range []string{
+		`{"startedAt": "2024-04-01T08:00:00Z"}`,
+		`{"startedAt": "2024-04-01T08:00:00Z", "endedAt": "2024-04-01T07:00:00Z"}`,
+	} {
+		if code := fetchStatus(t, update, cookie, "POST", "/workoutUpdate", body); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, code)
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 type item struct {
-	value      *UserSession
+	value      string
 	lastAccess int64
 }
 
@@ -45,23 +45,23 @@ func (m *TTLMap) Len() int {
 }
 
 // Put puts key k and value v
-func (m *TTLMap) Put(k string, v *UserSession) error {
+func (m *TTLMap) Put(k string, v string) error {
 	m.l.Lock()
+	defer m.l.Unlock()
 	_, ok := m.m[k]
 	var it *item
 	if !ok {
 		it = &item{value: v}
 		m.m[k] = it
 	} else {
-		return errors.New("failed to put key: " + k + ", value: " + v.Username)
+		return errors.New("failed to put key: " + k + ", value: " + v)
 	}
 	it.lastAccess = time.Now().Unix()
-	m.l.Unlock()
 	return nil
 }
 
 // Get gets value for key k
-func (m *TTLMap) Get(k string) (v *UserSession, err error) {
+func (m *TTLMap) Get(k string) (v string, err error) {
 	m.l.Lock()
 	if it, ok := m.m[k]; ok {
 		v = it.value
@@ -77,6 +77,7 @@ func (m *TTLMap) Get(k string) (v *UserSession, err error) {
 // Refresh updates the key k to newk
 func (m *TTLMap) Refresh(k, newk string) error {
 	m.l.Lock()
+	defer m.l.Unlock()
 	it, ok := m.m[k]
 	if ok {
 		it.lastAccess = time.Now().Unix()
@@ -87,6 +88,5 @@ func (m *TTLMap) Refresh(k, newk string) error {
 	} else {
 		return errors.New("failed to refresh key")
 	}
-	m.l.Unlock()
 	return nil
 }
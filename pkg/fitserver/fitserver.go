@@ -26,6 +26,18 @@ type (
 		ServerPort  int
 		LogFile     string
 		Quiet       bool
+		// Store is the storage backend, "memory" or "bolt".
+		Store string
+		// StorePath is the file the bolt store persists to.
+		StorePath string
+		// MovementsPath is a json or yaml movement bank to use instead of
+		// the embedded one, it is reloaded when it changes.
+		MovementsPath string
+		// MovementImagesPath is the directory containing the bank's
+		// movement_images, defaults to MovementsPath's directory.
+		MovementImagesPath string
+		// Admins are granted the admin role on startup.
+		Admins []string
 	}
 )
 
@@ -38,9 +50,19 @@ func RunServer() {
 // RunServerWithConfig runs the gofit server with a custom config
 func RunServerWithConfig(config Configuration) {
 	configureLogging(config)
+	store, err := server.NewStore(config.Store, config.StorePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
 	gw := server.Server{
 		BasePath: config.BasePath,
 		Port:     config.GatewayPort,
+		Store:    store,
+
+		MovementsPath:      config.MovementsPath,
+		MovementImagesPath: config.MovementImagesPath,
+		Admins:             config.Admins,
 	}
 
 	gw.Serve()